@@ -0,0 +1,240 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types reported in ReplicationStatus.
+const (
+	// ConditionReady is True when the source is replicated to every target.
+	ConditionReady = "Ready"
+	// ConditionDegraded is True when at least one target could not be replicated.
+	ConditionDegraded = "Degraded"
+	// ConditionPaused is True while replication of the source is paused.
+	ConditionPaused = "Paused"
+	// ConditionProgressing is True while a change propagates through waves.
+	ConditionProgressing = "Progressing"
+	// ConditionRolloutFailed is True when a workload restarted for the current
+	// content of the source did not finish its rollout.
+	ConditionRolloutFailed = "RolloutFailed"
+)
+
+// TargetState is the sync state of a single target namespace.
+// +kubebuilder:validation:Enum=Synced;Failed;Conflict;Pending
+type TargetState string
+
+const (
+	// TargetStateSynced means the replica matches the source.
+	TargetStateSynced TargetState = "Synced"
+	// TargetStateFailed means the last attempt to replicate failed.
+	TargetStateFailed TargetState = "Failed"
+	// TargetStateConflict means an unmanaged object blocks the replica.
+	TargetStateConflict TargetState = "Conflict"
+	// TargetStatePending means the target has not been replicated yet.
+	TargetStatePending TargetState = "Pending"
+)
+
+// WaveState is the propagation state of a wave.
+// +kubebuilder:validation:Enum=Pending;Progressing;Healthy;Halted
+type WaveState string
+
+const (
+	// WaveStatePending means the wave has not received the change yet.
+	WaveStatePending WaveState = "Pending"
+	// WaveStateProgressing means the wave received the change and its
+	// workloads are not healthy yet.
+	WaveStateProgressing WaveState = "Progressing"
+	// WaveStateHealthy means the wave received the change and its workloads
+	// are healthy.
+	WaveStateHealthy WaveState = "Healthy"
+	// WaveStateHalted means the workloads of the wave failed and propagation
+	// stopped.
+	WaveStateHalted WaveState = "Halted"
+)
+
+// JobState is the state of a Job run after a replica was updated.
+// +kubebuilder:validation:Enum=Running;Succeeded;Failed
+type JobState string
+
+const (
+	// JobStateRunning means the Job has not finished yet.
+	JobStateRunning JobState = "Running"
+	// JobStateSucceeded means the Job completed.
+	JobStateSucceeded JobState = "Succeeded"
+	// JobStateFailed means the Job failed.
+	JobStateFailed JobState = "Failed"
+)
+
+// SourceReference points at a Secret or ConfigMap in the namespace of the Replication.
+type SourceReference struct {
+	// Kind of the source object.
+	// +required
+	Kind SourceKind `json:"kind"`
+
+	// Name of the source object.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+}
+
+// ReplicationSpec defines the desired state of Replication.
+type ReplicationSpec struct {
+	// Source is the Secret or ConfigMap to replicate.
+	// +required
+	Source SourceReference `json:"source"`
+
+	// Targets defines where the source is replicated to.
+	// +required
+	Targets TargetSelector `json:"targets"`
+
+	// Options controls how the source is replicated.
+	// +optional
+	Options ReplicationOptions `json:"options,omitempty"`
+}
+
+// TargetStatus reports the sync state of a single target namespace.
+type TargetStatus struct {
+	// Namespace is the target namespace.
+	Namespace string `json:"namespace"`
+
+	// State is the sync state of the replica.
+	State TargetState `json:"state"`
+
+	// LastSyncedHash is the content hash of the last successfully written replica.
+	// +optional
+	LastSyncedHash string `json:"lastSyncedHash,omitempty"`
+
+	// LastSyncTime is the time the replica last changed state or content.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// LastError is the error of the last failed attempt.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// Failures is the number of consecutive failed attempts.
+	// +optional
+	Failures int32 `json:"failures,omitempty"`
+
+	// Job reports the Job run after the replica was last updated.
+	// +optional
+	Job *JobStatus `json:"job,omitempty"`
+
+	// WindowOpens is when the maintenance window opens that holds the latest
+	// change of the replica or the restart of its workloads.
+	// +optional
+	WindowOpens *metav1.Time `json:"windowOpens,omitempty"`
+}
+
+// JobStatus reports the Job run in a target namespace after its replica was
+// updated.
+type JobStatus struct {
+	// Name of the Job.
+	Name string `json:"name"`
+
+	// State of the Job.
+	State JobState `json:"state"`
+
+	// CompletionTime is the time the Job finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Message explains why the Job failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// WaveStatus reports the propagation state of a single wave.
+type WaveStatus struct {
+	// Name of the wave.
+	Name string `json:"name"`
+
+	// State is the propagation state of the wave.
+	State WaveState `json:"state"`
+
+	// Namespaces is the number of target namespaces in the wave.
+	// +optional
+	Namespaces int32 `json:"namespaces,omitempty"`
+
+	// Message explains the state, e.g. which workload is not healthy.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ReplicationStatus defines the observed state of Replication.
+type ReplicationStatus struct {
+	// ObservedGeneration is the generation the status was computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Targets reports the sync state of every target namespace.
+	// +listType=map
+	// +listMapKey=namespace
+	// +optional
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// Waves reports the progress of the latest change through the waves of
+	// the source, in propagation order.
+	// +optional
+	Waves []WaveStatus `json:"waves,omitempty"`
+
+	// Conditions represent the current state of the Replication.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.source.kind`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Replication is the Schema for the replications API.
+// Tenants create it next to a Secret or ConfigMap to declare where it is replicated to.
+type Replication struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// spec defines the desired state of Replication
+	// +required
+	Spec ReplicationSpec `json:"spec"`
+
+	// status defines the observed state of Replication
+	// +optional
+	Status ReplicationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReplicationList contains a list of Replication.
+type ReplicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Replication `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Replication{}, &ReplicationList{})
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SourceKind is a kind of resource that can be replicated.
+// +kubebuilder:validation:Enum=Secret;ConfigMap
+type SourceKind string
+
+const (
+	// SourceKindSecret selects Secrets.
+	SourceKindSecret SourceKind = "Secret"
+	// SourceKindConfigMap selects ConfigMaps.
+	SourceKindConfigMap SourceKind = "ConfigMap"
+)
+
+// ConflictPolicy defines what happens when a target namespace already contains
+// an object with the same name that was not created by the operator.
+// +kubebuilder:validation:Enum=Overwrite;Skip
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyOverwrite replaces the existing object and takes ownership of it.
+	ConflictPolicyOverwrite ConflictPolicy = "Overwrite"
+	// ConflictPolicySkip leaves the existing object untouched.
+	ConflictPolicySkip ConflictPolicy = "Skip"
+)
+
+// SourceSelector selects the Secrets and ConfigMaps a policy applies to.
+type SourceSelector struct {
+	// Namespace is the namespace the source objects live in.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Namespace string `json:"namespace"`
+
+	// Kinds limits the policy to Secrets, ConfigMaps or both. Empty selects both.
+	// +optional
+	Kinds []SourceKind `json:"kinds,omitempty"`
+
+	// Names selects source objects by name. Empty selects every name.
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Selector selects source objects by label. Empty selects every object.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// TargetSelector defines the namespaces a source is replicated to.
+type TargetSelector struct {
+	// Namespaces lists target namespaces by name or glob pattern, e.g. "team-*".
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// AllNamespaces replicates to every namespace except system and protected
+	// namespaces. It is not allowed in Replications.
+	// +optional
+	AllNamespaces bool `json:"allNamespaces,omitempty"`
+
+	// NamespaceSelector selects target namespaces by label. It is not allowed
+	// in Replications.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// ExcludeNamespaces lists namespaces that never receive a replica, even if
+	// another target or the source annotations select them.
+	// +optional
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+}
+
+// ReplicationOptions controls how sources are replicated.
+type ReplicationOptions struct {
+	// RolloutOnUpdate restarts workloads using the source when it changes.
+	// +optional
+	RolloutOnUpdate *bool `json:"rolloutOnUpdate,omitempty"`
+
+	// Keys limits replication to the listed data keys. Empty replicates all keys.
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+
+	// ConflictPolicy defines what happens when a target object already exists
+	// and was not created by the operator. Defaults to Overwrite, Replications
+	// always use Skip.
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Waves propagates changes to the target namespaces in order. A wave only
+	// receives a change after the workloads of the previous wave are healthy.
+	// Targets that are not part of any wave form a last, implicit wave.
+	// +optional
+	Waves []Wave `json:"waves,omitempty"`
+}
+
+// Wave is a group of target namespaces that receives changes of a source together.
+type Wave struct {
+	// Name identifies the wave in status and events.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	Name string `json:"name"`
+
+	// Namespaces lists the namespaces of the wave by name or glob pattern.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects the namespaces of the wave by label.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Delay is the minimum time between the previous wave becoming healthy
+	// and this wave receiving the change.
+	// +optional
+	Delay *metav1.Duration `json:"delay,omitempty"`
+}
+
+// ReplicationPolicySpec defines the desired state of ReplicationPolicy.
+type ReplicationPolicySpec struct {
+	// Sources selects the Secrets and ConfigMaps the policy applies to.
+	// +required
+	Sources SourceSelector `json:"sources"`
+
+	// Targets defines where the selected sources are replicated to.
+	// +required
+	Targets TargetSelector `json:"targets"`
+
+	// Options controls how the selected sources are replicated.
+	// +optional
+	Options ReplicationOptions `json:"options,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Source Namespace",type=string,JSONPath=`.spec.sources.namespace`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ReplicationPolicy is the Schema for the replicationpolicies API.
+// It replicates Secrets and ConfigMaps selected by a platform team without
+// requiring annotations on every source.
+type ReplicationPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// metadata is a standard object metadata
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// spec defines the desired state of ReplicationPolicy
+	// +required
+	Spec ReplicationPolicySpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReplicationPolicyList contains a list of ReplicationPolicy.
+type ReplicationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReplicationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReplicationPolicy{}, &ReplicationPolicyList{})
+}
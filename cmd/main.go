@@ -21,12 +21,15 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -37,7 +40,10 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
 	"github.com/Kammerdiener-Technologies/replizieren/internal/controller"
+	webhookv1 "github.com/Kammerdiener-Technologies/replizieren/internal/webhook/v1"
+	webhookv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -49,6 +55,7 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(replicationv1alpha1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -61,6 +68,18 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var protectedNamespaces string
+	var operatorServiceAccount string
+	var breakGlassGroups string
+	var resyncPeriod time.Duration
+	var paused bool
+	var controlConfigMap string
+	var dryRun bool
+	var workloadKinds string
+	var rolloutMaxConcurrency int
+	var rolloutInterval, rolloutTimeout time.Duration
+	var rolloutWaitReady bool
+	var revisionHistoryLimit int
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -79,6 +98,37 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&protectedNamespaces, "protected-namespaces", "",
+		"Comma-separated list of namespaces that are never replicated to, except when named by a ReplicationPolicy, "+
+			"in addition to the system namespaces.")
+	flag.StringVar(&operatorServiceAccount, "operator-service-account", defaultOperatorServiceAccount(),
+		"User name of the operator's service account, which is allowed to modify replicas. "+
+			"Defaults to the service account of the pod from POD_NAMESPACE and POD_SERVICE_ACCOUNT.")
+	flag.StringVar(&breakGlassGroups, "break-glass-groups", strings.Join(webhookv1.DefaultBreakGlassGroups, ","),
+		"Comma-separated list of user groups that are allowed to modify and delete replicas.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 10*time.Minute,
+		"Interval in which all sources are re-evaluated and their replicas repaired. Set to 0 to disable.")
+	flag.BoolVar(&paused, "paused", false,
+		"If set, replication is paused cluster-wide and no replicas are written.")
+	flag.StringVar(&controlConfigMap, "control-configmap", "replizieren-control",
+		"Name of the ConfigMap in the operator namespace (POD_NAMESPACE) whose key paused: \"true\" pauses "+
+			"replication cluster-wide. Set to an empty string to disable.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"If set, replicas are not written and workloads are not restarted. "+
+			"Every write is logged and recorded as event instead.")
+	flag.StringVar(&workloadKinds, "workload-kinds", "",
+		"Comma-separated list of custom workload kinds restarted on updates, in the form "+
+			"group/version/Kind=<JSONPath of the pod template>, e.g. argoproj.io/v1alpha1/Rollout=.spec.template.")
+	flag.IntVar(&rolloutMaxConcurrency, "rollout-max-concurrency", 0,
+		"Maximum number of namespaces whose workloads are restarted at the same time. 0 means no limit.")
+	flag.DurationVar(&rolloutInterval, "rollout-interval", 0,
+		"Pause between restarting the workloads of a source in consecutive namespaces.")
+	flag.BoolVar(&rolloutWaitReady, "rollout-wait-ready", false,
+		"If set, restarted workloads must be ready before the workloads in the next namespace are restarted.")
+	flag.DurationVar(&rolloutTimeout, "rollout-timeout", 10*time.Minute,
+		"Maximum time to wait for restarted workloads to become ready.")
+	flag.IntVar(&revisionHistoryLimit, "revision-history-limit", 0,
+		"Number of revisions of the replicated payload kept per source in the operator namespace. 0 keeps no revisions.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -87,6 +137,15 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	customWorkloadKinds, err := controller.ParseWorkloadKinds(workloadKinds)
+	if err != nil {
+		setupLog.Error(err, "invalid --workload-kinds")
+		os.Exit(1)
+	}
+	for _, ns := range controller.SplitList(protectedNamespaces) {
+		controller.ProtectedNamespaces[ns] = true
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -176,6 +235,7 @@ func main() {
 		})
 	}
 
+	ctx := ctrl.SetupSignalHandler()
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
@@ -200,27 +260,102 @@ func main() {
 		os.Exit(1)
 	}
 
+	pause := &controller.Pause{Paused: paused}
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" && controlConfigMap != "" {
+		pause.ConfigMap = types.NamespacedName{Namespace: namespace, Name: controlConfigMap}
+	}
+
+	rollouts := &controller.RolloutScheduler{
+		Client:         mgr.GetClient(),
+		MaxConcurrency: rolloutMaxConcurrency,
+		Interval:       rolloutInterval,
+		WaitReady:      rolloutWaitReady,
+		Timeout:        rolloutTimeout,
+	}
+	if err := mgr.Add(rollouts); err != nil {
+		setupLog.Error(err, "unable to add rollout scheduler to manager")
+		os.Exit(1)
+	}
+
+	waves := &controller.WaveTracker{}
+	revisions := &controller.RevisionHistory{Namespace: os.Getenv("POD_NAMESPACE"), Limit: revisionHistoryLimit}
+	rolloutTracker := &controller.RolloutTracker{}
+	seeds := &controller.ChecksumSeeder{}
+	reloads, err := controller.NewReloadIndex(ctx, mgr.GetFieldIndexer(), customWorkloadKinds)
+	if err != nil {
+		setupLog.Error(err, "unable to index workloads")
+		os.Exit(1)
+	}
+
+	var resyncer *controller.Resyncer
+	if resyncPeriod > 0 {
+		resyncer = &controller.Resyncer{Client: mgr.GetClient(), Pause: pause, Period: resyncPeriod}
+	}
+	syncOptions := controller.SyncOptions{
+		Recorder:       mgr.GetEventRecorder("replizieren"),
+		Pause:          pause,
+		DryRun:         dryRun,
+		WorkloadKinds:  customWorkloadKinds,
+		Rollouts:       rollouts,
+		Waves:          waves,
+		Revisions:      revisions,
+		RolloutTracker: rolloutTracker,
+		Seeds:          seeds,
+		Reloads:        reloads,
+		Resyncer:       resyncer,
+	}
 	if err := (&controller.SecretReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		SyncOptions: syncOptions,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Secret")
 		os.Exit(1)
 	}
 	if err := (&controller.ConfigMapWatcherReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		SyncOptions: syncOptions,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ConfigMapWatcher")
 		os.Exit(1)
 	}
 	if err := (&controller.NamespaceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Pause:    pause,
+		DryRun:   dryRun,
+		Recorder: mgr.GetEventRecorder("replizieren"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Namespace")
 		os.Exit(1)
 	}
+	if resyncer != nil {
+		if err := mgr.Add(resyncer); err != nil {
+			setupLog.Error(err, "unable to add resync to manager")
+			os.Exit(1)
+		}
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		webhookOpts := webhookv1.Options{
+			ProtectedNamespaces: controller.SplitList(protectedNamespaces),
+			OperatorUsername:    operatorServiceAccount,
+			BreakGlassGroups:    controller.SplitList(breakGlassGroups),
+		}
+		if err := webhookv1.SetupSecretWebhookWithManager(mgr, webhookOpts); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Secret")
+			os.Exit(1)
+		}
+		if err := webhookv1.SetupConfigMapWebhookWithManager(mgr, webhookOpts); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ConfigMap")
+			os.Exit(1)
+		}
+		if err := webhookv1alpha1.SetupReplicationWebhookWithManager(mgr, webhookOpts); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Replication")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -249,8 +384,18 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// defaultOperatorServiceAccount returns the user name of the service account
+// the operator runs as, if the pod exposes it through the downward API.
+func defaultOperatorServiceAccount() string {
+	namespace, name := os.Getenv("POD_NAMESPACE"), os.Getenv("POD_SERVICE_ACCOUNT")
+	if namespace == "" || name == "" {
+		return ""
+	}
+	return "system:serviceaccount:" + namespace + ":" + name
+}
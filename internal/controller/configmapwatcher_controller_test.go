@@ -296,7 +296,7 @@ var _ = Describe("ConfigMap Replication", func() {
 			if d.Spec.Template.Annotations == nil {
 				return ""
 			}
-			return d.Spec.Template.Annotations["configmap.restartedAt"]
+			return d.Spec.Template.Annotations[checksumAnnotationKey(cm)]
 		}, timeout, interval).ShouldNot(BeEmpty())
 	})
 
@@ -368,7 +368,7 @@ var _ = Describe("ConfigMap Replication", func() {
 			if d.Spec.Template.Annotations == nil {
 				return ""
 			}
-			return d.Spec.Template.Annotations["configmap.restartedAt"]
+			return d.Spec.Template.Annotations[checksumAnnotationKey(cm)]
 		}, timeout, interval).ShouldNot(BeEmpty())
 	})
 })
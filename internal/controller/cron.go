@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSearchLimit bounds the search for the next match of a schedule, so
+// schedules that never match, e.g. "0 0 30 2 *", terminate.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronField is the set of values a field of a schedule matches.
+type cronField uint64
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+// cronSchedule is a standard five-field cron schedule: minute, hour, day of
+// month, month and day of week.
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+	// Like cron, a day matches if either the day of month or the day of week
+	// matches when both are restricted.
+	domAny, dowAny bool
+}
+
+var (
+	cronMonths = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	cronDays   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// parseCron parses a five-field cron schedule. Fields support "*", values,
+// ranges, steps and lists, e.g. "*/15 22-23 * * mon-fri". Months and days of
+// the week may be given by their English three-letter names; Sunday is 0 or 7.
+func parseCron(expression string) (cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("invalid cron schedule %q: expected 5 fields", expression)
+	}
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return cronSchedule{}, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return cronSchedule{}, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return cronSchedule{}, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12, cronMonths); err != nil {
+		return cronSchedule{}, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7, cronDays); err != nil {
+		return cronSchedule{}, err
+	}
+	if s.dow.has(7) {
+		s.dow |= 1
+	}
+	s.domAny = strings.HasPrefix(fields[2], "*")
+	s.dowAny = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps
+// between min and max. Names are matched case-insensitively and numbered from
+// min.
+func parseCronField(field string, minValue, maxValue int, names []string) (cronField, error) {
+	var result cronField
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in cron field %q", field)
+			}
+		}
+
+		low, high := minValue, maxValue
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseCronValue(from, minValue, maxValue, names); err != nil {
+				return 0, fmt.Errorf("invalid cron field %q: %w", field, err)
+			}
+			high = low
+			if isRange {
+				if high, err = parseCronValue(to, minValue, maxValue, names); err != nil {
+					return 0, fmt.Errorf("invalid cron field %q: %w", field, err)
+				}
+			} else if hasStep {
+				high = maxValue
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range in cron field %q", field)
+			}
+		}
+		for v := low; v <= high; v += step {
+			result |= 1 << uint(v)
+		}
+	}
+	return result, nil
+}
+
+// parseCronValue parses a number or name between min and max.
+func parseCronValue(value string, minValue, maxValue int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(value, name) {
+			return minValue + i, nil
+		}
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil || v < minValue || v > maxValue {
+		return 0, fmt.Errorf("value %q out of range %d-%d", value, minValue, maxValue)
+	}
+	return v, nil
+}
+
+// dayMatches returns true if the schedule runs on the day of t.
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom.has(t.Day()), s.dow.has(int(t.Weekday()))
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns the first time after t the schedule matches, in the location
+// of t. It returns the zero time if the schedule never matches.
+func (s cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+	for t.Before(limit) {
+		if !s.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.hour.has(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !s.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
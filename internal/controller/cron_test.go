@@ -0,0 +1,62 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronSchedule_Next(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2025, time.January, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{schedule: "* * * * *", want: time.Date(2025, time.January, 1, 10, 31, 0, 0, time.UTC)},
+		{schedule: "*/15 * * * *", want: time.Date(2025, time.January, 1, 10, 45, 0, 0, time.UTC)},
+		{schedule: "0 22 * * *", want: time.Date(2025, time.January, 1, 22, 0, 0, 0, time.UTC)},
+		{schedule: "0 9 * * *", want: time.Date(2025, time.January, 2, 9, 0, 0, 0, time.UTC)},
+		{schedule: "0 22 * * sat,sun", want: time.Date(2025, time.January, 4, 22, 0, 0, 0, time.UTC)},
+		{schedule: "0 6 * * 7", want: time.Date(2025, time.January, 5, 6, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 1 mar *", want: time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{schedule: "30 2 29 2 *", want: time.Date(2028, time.February, 29, 2, 30, 0, 0, time.UTC)},
+		// Either the day of month or the day of week matches
+		{schedule: "0 0 15 * fri", want: time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{schedule: "0 0 30 2 *", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.schedule)
+		if err != nil {
+			t.Fatalf("parseCron(%q): unexpected error: %v", tt.schedule, err)
+		}
+		if got := schedule.next(from); !got.Equal(tt.want) {
+			t.Errorf("next(%q) = %v, want %v", tt.schedule, got, tt.want)
+		}
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, schedule := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"5-1 * * * *", "*/0 * * * *", "* * * * funday"} {
+		if _, err := parseCron(schedule); err == nil {
+			t.Errorf("expected error for %q", schedule)
+		}
+	}
+}
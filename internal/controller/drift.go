@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Event reasons for drift
+const (
+	reasonDriftDetected = "DriftDetected"
+)
+
+// isReplicaPredicate selects replicas created by the operator.
+var isReplicaPredicate = predicate.NewPredicateFuncs(IsReplica)
+
+// isSourcePredicate selects everything but replicas and revisions.
+var isSourcePredicate = predicate.NewPredicateFuncs(func(obj client.Object) bool {
+	return !IsReplica(obj) && !IsRevision(obj)
+})
+
+// enqueueSource adds the source of a replica to the queue.
+func enqueueSource(obj client.Object, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if ref, ok := SourceOf(obj); ok {
+		q.Add(reconcile.Request{NamespacedName: ref})
+	}
+}
+
+// replicaHandler maps replica events to their source. Deleted replicas are
+// recorded as drift before the source is enqueued to re-create them.
+func (s *sourceSyncer) replicaHandler() handler.EventHandler {
+	return handler.Funcs{
+		CreateFunc: func(_ context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueSource(e.Object, q)
+		},
+		UpdateFunc: func(_ context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueSource(e.ObjectNew, q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			s.recordDeletedReplica(ctx, e.Object)
+			enqueueSource(e.Object, q)
+		},
+		GenericFunc: func(_ context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueSource(e.Object, q)
+		},
+	}
+}
+
+// recordDeletedReplica records the deletion of a replica as drift, unless the
+// namespace of the replica is being deleted or no longer selected by the source,
+// or replication is paused.
+func (s *sourceSyncer) recordDeletedReplica(ctx context.Context, replica client.Object) {
+	ref, ok := SourceOf(replica)
+	if !ok {
+		return
+	}
+
+	var namespace corev1.Namespace
+	if err := s.Get(ctx, client.ObjectKey{Name: replica.GetNamespace()}, &namespace); err != nil ||
+		!namespace.DeletionTimestamp.IsZero() {
+		return
+	}
+
+	source := newSourceObject(SourceKindOf(replica))
+	if err := s.Get(ctx, ref, source); err != nil || IsPaused(source) {
+		return
+	}
+	if paused, _, err := s.Pause.GloballyPaused(ctx, s.Client); err != nil || paused {
+		return
+	}
+
+	// Replicas in namespaces that are no longer selected were pruned
+	config, err := ResolveReplicationConfig(ctx, s.Client, source)
+	if err != nil || !config.MatchesNamespace(&namespace, source.GetNamespace()) {
+		return
+	}
+
+	log.FromContext(ctx).Info("Replica was deleted, re-creating",
+		"namespace", replica.GetNamespace(), "name", replica.GetName())
+	s.recordDrift(source, replica, driftDeleted)
+}
+
+// recordDrift exports drift of a replica as metric and event on the source.
+func (s *sourceSyncer) recordDrift(source, replica client.Object, reason string) {
+	replicaDriftTotal.WithLabelValues(string(SourceKindOf(source)), replica.GetNamespace(), reason).Inc()
+	if s.Recorder != nil {
+		s.Recorder.Eventf(source, replica, corev1.EventTypeWarning, reasonDriftDetected, "Restore",
+			"Replica in namespace %s was %s outside of replizieren, restoring it", replica.GetNamespace(), reason)
+	}
+}
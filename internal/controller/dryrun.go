@@ -0,0 +1,131 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DryRunKey makes replication of a single source a dry run when set to "true".
+const DryRunKey = "replizieren.dev/dry-run"
+
+// Event reasons for dry runs
+const (
+	reasonDryRun = "DryRun"
+)
+
+// IsDryRun returns true if replication of the source is a dry run by annotation.
+func IsDryRun(source client.Object) bool {
+	return source.GetAnnotations()[DryRunKey] == "true"
+}
+
+// writerFor returns the client used to write replicas and statuses and restart
+// workloads of the source. In a dry run, writes are only logged and recorded as
+// events on the source. Source may be nil for writes not caused by a source.
+func writerFor(c client.Client, recorder events.EventRecorder, source client.Object, dryRun bool) client.Client {
+	if !dryRun && (source == nil || !IsDryRun(source)) {
+		return c
+	}
+	return &dryRunClient{Client: c, recorder: recorder, source: source}
+}
+
+// dryRunClient sends all writes as server-side dry runs, so they are validated
+// by the API server but never persisted. Status updates are dropped.
+type dryRunClient struct {
+	client.Client
+	recorder events.EventRecorder
+	source   client.Object
+}
+
+// Create records that obj would be created.
+func (c *dryRunClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.record(ctx, "Create", "create", obj)
+	return c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Update records that obj would be updated.
+func (c *dryRunClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.record(ctx, "Update", "update", obj)
+	return c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Patch records that obj would be patched. Workloads are only patched to
+// restart them.
+func (c *dryRunClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	switch obj.(type) {
+	case *corev1.Secret, *corev1.ConfigMap:
+		c.record(ctx, "Patch", "patch", obj)
+	default:
+		c.record(ctx, "Restart", "restart", obj)
+	}
+	return c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+// Delete records that obj would be deleted.
+func (c *dryRunClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.record(ctx, "Delete", "delete", obj)
+	return c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Status drops all status writes.
+func (c *dryRunClient) Status() client.SubResourceWriter {
+	return dryRunStatusWriter{}
+}
+
+// record logs the write and records it as event on the source.
+func (c *dryRunClient) record(ctx context.Context, action, verb string, obj client.Object) {
+	kind := ""
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = gvk.Kind
+	}
+	log.FromContext(ctx).Info("Dry run, would "+verb, "kind", kind,
+		"namespace", obj.GetNamespace(), "name", obj.GetName())
+	if c.recorder != nil && c.source != nil {
+		c.recorder.Eventf(c.source, obj, corev1.EventTypeNormal, reasonDryRun, action,
+			"Dry run, would %s %s %s/%s", verb, kind, obj.GetNamespace(), obj.GetName())
+	}
+}
+
+// dryRunStatusWriter drops all status writes.
+type dryRunStatusWriter struct{}
+
+// Create does nothing.
+func (dryRunStatusWriter) Create(context.Context, client.Object, client.Object, ...client.SubResourceCreateOption) error {
+	return nil
+}
+
+// Update does nothing.
+func (dryRunStatusWriter) Update(context.Context, client.Object, ...client.SubResourceUpdateOption) error {
+	return nil
+}
+
+// Patch does nothing.
+func (dryRunStatusWriter) Patch(context.Context, client.Object, client.Patch, ...client.SubResourcePatchOption) error {
+	return nil
+}
+
+// Apply does nothing.
+func (dryRunStatusWriter) Apply(context.Context, runtime.ApplyConfiguration, ...client.SubResourceApplyOption) error {
+	return nil
+}
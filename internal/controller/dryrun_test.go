@@ -0,0 +1,80 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSyncSource_DryRun(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey:       "target-ns",
+				RolloutOnUpdateKey: "true",
+				DryRunKey:          "true",
+			},
+		},
+		Data: map[string][]byte{"key": []byte("shared")},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "target-ns"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:    "app",
+						EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}}}},
+					}},
+				},
+			},
+		},
+	}
+	f := newSyncFixture(t, source, deploy)
+
+	results, _ := f.sync(source)
+	if results["target-ns"].Outcome != ReplicaCreated {
+		t.Errorf("expected dry run to report the replica as created, got %s", results["target-ns"].Outcome)
+	}
+	if f.exists(&corev1.Secret{}, "target-ns", "creds") {
+		t.Error("expected dry run to not create the replica")
+	}
+	f.get(deploy)
+	if len(deploy.Spec.Template.Annotations) != 0 {
+		t.Errorf("expected dry run to not restart the deployment, got %v", deploy.Spec.Template.Annotations)
+	}
+
+	// Without the annotation, the global flag makes it a dry run
+	delete(source.Annotations, DryRunKey)
+	f.syncer.DryRun = true
+	f.sync(source)
+	if f.exists(&corev1.Secret{}, "target-ns", "creds") {
+		t.Error("expected global dry run to not create the replica")
+	}
+
+	f.syncer.DryRun = false
+	f.sync(source)
+	if !f.exists(&corev1.Secret{}, "target-ns", "creds") {
+		t.Error("expected replica to be created without dry run")
+	}
+}
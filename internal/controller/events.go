@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Event reasons for replication and rollout actions
+const (
+	reasonReplicated        = "Replicated"
+	reasonReplicationFailed = "ReplicationFailed"
+	reasonPruned            = "Pruned"
+	reasonConflict          = "Conflict"
+	reasonRestartedBy       = "RestartedBy"
+)
+
+// recordReplication records the outcome of replicating the source into a
+// namespace as event on the source. Replicas restored after drift are
+// recorded by recordDrift, unchanged and held replicas are not recorded.
+func recordReplication(recorder events.EventRecorder, source client.Object, ns string, outcome ReplicaOutcome, err error, dryRun bool) {
+	if recorder == nil {
+		return
+	}
+	switch {
+	case err != nil:
+		recorder.Eventf(source, nil, corev1.EventTypeWarning, reasonReplicationFailed, "Replicate",
+			"Failed to replicate to namespace %s: %v", ns, err)
+	case outcome == ReplicaConflict:
+		recorder.Eventf(source, nil, corev1.EventTypeWarning, reasonConflict, "Replicate",
+			"Namespace %s already contains a %s %s not managed by replizieren, skipping",
+			ns, SourceKindOf(source), source.GetName())
+	case dryRun:
+		// Writes of dry runs are recorded by the dry run client
+	case outcome == ReplicaCreated:
+		recorder.Eventf(source, nil, corev1.EventTypeNormal, reasonReplicated, "Replicate",
+			"Created replica in namespace %s", ns)
+	case outcome == ReplicaUpdated:
+		recorder.Eventf(source, nil, corev1.EventTypeNormal, reasonReplicated, "Replicate",
+			"Updated replica in namespace %s", ns)
+	}
+}
+
+// recordRestarts records an event on every restarted workload that refers to
+// the source which caused the restart.
+func recordRestarts(recorder events.EventRecorder, source client.Object, restarted []Workload) {
+	if recorder == nil || source == nil {
+		return
+	}
+	for _, workload := range restarted {
+		recorder.Eventf(workload.Object, source, corev1.EventTypeNormal, reasonRestartedBy, "Restart",
+			"Restarted for a change of %s %s/%s", SourceKindOf(source), source.GetNamespace(), source.GetName())
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestSyncSource_RecordsEvents(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey:       "app-ns,other-ns",
+				ConflictPolicyKey:  string(replicationv1alpha1.ConflictPolicySkip),
+				RolloutOnUpdateKey: "true",
+			},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	unmanaged := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "other-ns"}}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "app-ns"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	f := newSyncFixture(t, source, unmanaged, deploy)
+
+	f.sync(source)
+	f.expectEvents(
+		"Normal Replicated Created replica in namespace app-ns",
+		"Warning Conflict Namespace other-ns already contains a Secret creds",
+		"Normal RestartedBy Restarted for a change of Secret source-ns/creds",
+	)
+
+	// Updates are recorded, unchanged replicas are not
+	source.Data["password"] = []byte("v2")
+	f.sync(source)
+	f.expectEvents(
+		"Normal Replicated Updated replica in namespace app-ns",
+		"Warning Conflict",
+		"Normal RestartedBy",
+	)
+
+	// Dry runs only record what they would have done
+	f.syncer.DryRun = true
+	source.Data["password"] = []byte("v3")
+	f.sync(source)
+	for len(f.recorder.Events) > 0 {
+		if event := <-f.recorder.Events; strings.Contains(event, reasonReplicated) || strings.Contains(event, reasonRestartedBy) {
+			t.Errorf("expected no %s or %s events during a dry run, got %q", reasonReplicated, reasonRestartedBy, event)
+		}
+	}
+}
+
+func TestNamespaceReconciler_RecordsEvents(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "creds",
+			Namespace:   "source-ns",
+			Annotations: map[string]string{ReplicateKey: "team-*"},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	team := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+	f := newSyncFixture(t, source, team)
+	reconciler := &NamespaceReconciler{Client: f.client, Scheme: f.scheme, Recorder: f.recorder}
+	reconcile := func() {
+		t.Helper()
+		if _, err := reconciler.Reconcile(f.ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: team.Name}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	reconcile()
+	f.expectEvents("Normal Replicated Created replica in namespace team-a")
+
+	// The replica is pruned once the source no longer selects the namespace
+	source.Annotations[ReplicateKey] = "other-*"
+	f.update(source)
+	reconcile()
+	f.expectEvents("Normal Pruned Removed replica from namespace team-a")
+}
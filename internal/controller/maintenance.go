@@ -0,0 +1,222 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Annotations that restrict changes to maintenance windows. They may be set
+// on a source, for all of its targets, or on a Namespace, for all replicas and
+// workloads in it.
+const (
+	// MaintenanceWindowKey lists the windows, e.g. "0 22 * * mon-fri 4h".
+	MaintenanceWindowKey = "replizieren.dev/maintenance-window"
+	// MaintenanceWindowScopeKey is "All" to hold replica updates and restarts
+	// outside the windows, or "Rollout" to only hold restarts.
+	MaintenanceWindowScopeKey = "replizieren.dev/maintenance-window-scope"
+	// BreakGlassKey on a source applies its changes regardless of windows. The
+	// value is "true" or the reason for the bypass.
+	BreakGlassKey = "replizieren.dev/break-glass"
+)
+
+// Scopes of maintenance windows
+const (
+	WindowScopeAll     = "All"
+	WindowScopeRollout = "Rollout"
+)
+
+// Event reasons for maintenance windows
+const (
+	reasonWindowBypassed = "MaintenanceWindowBypassed"
+)
+
+// cronTimeZonePrefix selects the time zone of a window, e.g.
+// "CRON_TZ=Europe/Berlin 0 22 * * * 4h".
+const cronTimeZonePrefix = "CRON_TZ="
+
+// MaintenanceWindow opens on a cron schedule and stays open for a duration.
+type MaintenanceWindow struct {
+	schedule cronSchedule
+	location *time.Location
+	duration time.Duration
+}
+
+// ParseMaintenanceWindows parses the maintenance-window annotation. Windows
+// are separated by semicolons; each is a five-field cron schedule followed by
+// the duration the window stays open, optionally prefixed by CRON_TZ=<zone>.
+// Schedules are evaluated in UTC by default.
+func ParseMaintenanceWindows(value string) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	for _, entry := range strings.Split(value, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		window := MaintenanceWindow{location: time.UTC}
+		if zone, ok := strings.CutPrefix(fields[0], cronTimeZonePrefix); ok {
+			location, err := time.LoadLocation(zone)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time zone %q", zone)
+			}
+			window.location = location
+			fields = fields[1:]
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("invalid maintenance window %q: expected a cron schedule and a duration", strings.TrimSpace(entry))
+		}
+		duration, err := time.ParseDuration(fields[5])
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid duration %q: must be a positive duration, e.g. 4h", fields[5])
+		}
+		window.duration = duration
+		if window.schedule, err = parseCron(strings.Join(fields[:5], " ")); err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// nextOpen returns now if the window is open, or when it opens next. It
+// returns the zero time if the window never opens.
+func (w MaintenanceWindow) nextOpen(now time.Time) time.Time {
+	now = now.In(w.location)
+	// The window is open if it started at most its duration ago
+	if start := w.schedule.next(now.Add(-w.duration)); !start.IsZero() && !start.After(now) {
+		return now
+	}
+	return w.schedule.next(now)
+}
+
+// maintenanceWindows are the windows defined by a source or Namespace.
+type maintenanceWindows struct {
+	windows []MaintenanceWindow
+	// rolloutOnly holds only restarts outside the windows.
+	rolloutOnly bool
+}
+
+// windowsOf returns the maintenance windows of a source or Namespace. Invalid
+// windows of sources are rejected by the webhook; they are ignored here.
+func windowsOf(obj client.Object) maintenanceWindows {
+	annotations := obj.GetAnnotations()
+	windows, err := ParseMaintenanceWindows(annotations[MaintenanceWindowKey])
+	if err != nil {
+		return maintenanceWindows{}
+	}
+	return maintenanceWindows{windows: windows, rolloutOnly: annotations[MaintenanceWindowScopeKey] == WindowScopeRollout}
+}
+
+// nextOpen returns now if any window is open or no window is defined, or when
+// the next window opens.
+func (w maintenanceWindows) nextOpen(now time.Time) time.Time {
+	if len(w.windows) == 0 {
+		return now
+	}
+	var next time.Time
+	for _, window := range w.windows {
+		open := window.nextOpen(now)
+		if !open.IsZero() && (next.IsZero() || open.Before(next)) {
+			next = open
+		}
+	}
+	return next
+}
+
+// IsBreakGlass returns true if the changes of the source bypass maintenance
+// windows.
+func IsBreakGlass(source client.Object) bool {
+	value := strings.TrimSpace(source.GetAnnotations()[BreakGlassKey])
+	return value != "" && value != "false"
+}
+
+// windowGate decides when the replicas and workloads of the namespaces of a
+// source may change.
+type windowGate struct {
+	now        time.Time
+	source     maintenanceWindows
+	namespaces map[string]maintenanceWindows
+	bypass     bool
+}
+
+// newWindowGate reads the windows of the source and of all namespaces.
+func newWindowGate(ctx context.Context, c client.Client, source client.Object, now time.Time) (*windowGate, error) {
+	gate := &windowGate{now: now, source: windowsOf(source), namespaces: map[string]maintenanceWindows{}, bypass: IsBreakGlass(source)}
+	var nsList corev1.NamespaceList
+	if err := c.List(ctx, &nsList); err != nil {
+		return nil, err
+	}
+	for i := range nsList.Items {
+		if windows := windowsOf(&nsList.Items[i]); len(windows.windows) > 0 {
+			gate.namespaces[nsList.Items[i].Name] = windows
+		}
+	}
+	return gate, nil
+}
+
+// restricted returns true if a window applies to the namespace.
+func (g *windowGate) restricted(namespace string) bool {
+	return len(g.source.windows) > 0 || len(g.namespaces[namespace].windows) > 0
+}
+
+// opens returns now if the namespace may change immediately, or the earliest
+// time it may change. With rollout false, only windows that also hold replica
+// updates count. The zero time means never.
+func (g *windowGate) opens(namespace string, rollout bool) time.Time {
+	at := g.now
+	for _, windows := range []maintenanceWindows{g.source, g.namespaces[namespace]} {
+		if windows.rolloutOnly && !rollout {
+			continue
+		}
+		next := windows.nextOpen(g.now)
+		if next.IsZero() {
+			return next
+		}
+		if next.After(at) {
+			at = next
+		}
+	}
+	return at
+}
+
+// writeOpens returns when the replica in the namespace may be updated.
+func (g *windowGate) writeOpens(namespace string) time.Time {
+	if g.bypass {
+		return g.now
+	}
+	return g.opens(namespace, false)
+}
+
+// rolloutOpens returns when the workloads in the namespace may be restarted.
+func (g *windowGate) rolloutOpens(namespace string) time.Time {
+	if g.bypass {
+		return g.now
+	}
+	return g.opens(namespace, true)
+}
+
+// bypassed returns true if the break-glass annotation let a change through
+// that a window would have held.
+func (g *windowGate) bypassed(namespace string) bool {
+	return g.bypass && !g.opens(namespace, true).Equal(g.now)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestMaintenanceWindow_NextOpen(t *testing.T) {
+	windows, err := ParseMaintenanceWindows("0 22 * * mon-fri 4h; CRON_TZ=Europe/Berlin 0 10 * * sat 1h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mw := maintenanceWindows{windows: windows}
+
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		// Wednesday before, inside and after the weekday window
+		{now: time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC), want: time.Date(2025, time.January, 1, 22, 0, 0, 0, time.UTC)},
+		{now: time.Date(2025, time.January, 1, 23, 0, 0, 0, time.UTC), want: time.Date(2025, time.January, 1, 23, 0, 0, 0, time.UTC)},
+		{now: time.Date(2025, time.January, 2, 1, 59, 0, 0, time.UTC), want: time.Date(2025, time.January, 2, 1, 59, 0, 0, time.UTC)},
+		{now: time.Date(2025, time.January, 2, 2, 0, 0, 0, time.UTC), want: time.Date(2025, time.January, 2, 22, 0, 0, 0, time.UTC)},
+		// Saturday, the window in Berlin opens at 9:00 UTC
+		{now: time.Date(2025, time.January, 4, 6, 0, 0, 0, time.UTC), want: time.Date(2025, time.January, 4, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := mw.nextOpen(tt.now); !got.Equal(tt.want) {
+			t.Errorf("nextOpen(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+
+	if got := (maintenanceWindows{}).nextOpen(tests[0].now); !got.Equal(tests[0].now) {
+		t.Error("expected no windows to always be open")
+	}
+
+	for _, value := range []string{"0 22 * * *", "0 22 * * * -1h", "CRON_TZ=Mars/Olympus 0 22 * * * 1h"} {
+		if _, err := ParseMaintenanceWindows(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+// closedWindow returns a one-minute window that opens in about 30 minutes.
+func closedWindow() string {
+	return fmt.Sprintf("%d * * * * 1m", (time.Now().UTC().Minute()+30)%60)
+}
+
+func TestSyncSource_MaintenanceWindow(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey:       "prod",
+				RolloutOnUpdateKey: "true",
+			},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	prod := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "prod",
+		Annotations: map[string]string{MaintenanceWindowKey: closedWindow()},
+	}}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "prod"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	f := newSyncFixture(t, source, prod, deploy)
+
+	// New replicas are created outside of windows, restarts wait
+	results, requeueAfter := f.sync(source)
+	if results["prod"].Outcome != ReplicaCreated || results["prod"].WindowOpens.IsZero() {
+		t.Errorf("expected replica to be created with a held restart, got %+v", results["prod"])
+	}
+	if f.checksum(source, "prod", "app") != "" {
+		t.Error("expected no restart outside of the window")
+	}
+	if requeueAfter <= 0 || requeueAfter > time.Hour {
+		t.Errorf("expected requeue when the window opens, got %v", requeueAfter)
+	}
+
+	// Updates are held
+	source.Data["password"] = []byte("v2")
+	f.update(source)
+	results, _ = f.sync(source)
+	if results["prod"].Outcome != ReplicaPending || results["prod"].WindowOpens.IsZero() {
+		t.Errorf("expected held update, got %+v", results["prod"])
+	}
+	if f.secretData("prod", "creds", "password") != "v1" {
+		t.Error("expected replica to keep its content outside of the window")
+	}
+	reconciler := &NamespaceReconciler{Client: f.client, Scheme: f.scheme, Recorder: f.recorder}
+	if _, err := reconciler.Reconcile(f.ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: prod.Name}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.secretData("prod", "creds", "password") != "v1" {
+		t.Error("expected namespace changes to not update the replica outside of the window")
+	}
+
+	// Restarts only
+	prod.Annotations[MaintenanceWindowScopeKey] = WindowScopeRollout
+	f.update(prod)
+	results, _ = f.sync(source)
+	if results["prod"].Outcome != ReplicaUpdated || results["prod"].WindowOpens.IsZero() {
+		t.Errorf("expected update with a held restart, got %+v", results["prod"])
+	}
+	if f.secretData("prod", "creds", "password") != "v2" || f.checksum(source, "prod", "app") != "" {
+		t.Error("expected replica to be updated without restart")
+	}
+
+	// Break glass applies everything
+	source.Annotations[BreakGlassKey] = "leaked credentials"
+	f.sync(source)
+	if f.checksum(source, "prod", "app") == "" {
+		t.Error("expected break glass to restart the workloads outside of the window")
+	}
+}
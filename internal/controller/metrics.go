@@ -0,0 +1,131 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Drift reasons used as metric label values.
+const (
+	driftModified = "modified"
+	driftDeleted  = "deleted"
+)
+
+// Rollout outcomes used as metric label values.
+const (
+	rolloutComplete = "complete"
+	rolloutFailed   = "failed"
+)
+
+var (
+	// replicaDriftTotal counts replicas that were changed or deleted by someone
+	// other than the operator.
+	replicaDriftTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "replizieren_replica_drift_total",
+			Help: "Number of replicas that were modified or deleted outside of replizieren",
+		},
+		[]string{"kind", "namespace", "reason"},
+	)
+
+	// replicaSyncErrorsTotal counts failed attempts to write a replica.
+	replicaSyncErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "replizieren_replica_sync_errors_total",
+			Help: "Number of failed attempts to write a replica",
+		},
+		[]string{"kind", "namespace"},
+	)
+)
+
+var (
+	// rolloutsTotal counts verified rollouts of restarted workloads.
+	rolloutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "replizieren_rollouts_total",
+			Help: "Number of rollouts of restarted workloads that completed or failed",
+		},
+		[]string{"kind", "namespace", "outcome"},
+	)
+
+	// rolloutDurationSeconds observes how long restarted workloads took to
+	// complete their rollout.
+	rolloutDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "replizieren_rollout_duration_seconds",
+			Help:    "Time from observing the rollout of a restarted workload until it completed",
+			Buckets: []float64{5, 15, 30, 60, 120, 300, 600, 1200},
+		},
+		[]string{"kind"},
+	)
+)
+
+var (
+	// resyncRunsTotal counts completed resync passes.
+	resyncRunsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "replizieren_resync_runs_total",
+			Help: "Number of completed resync passes",
+		},
+	)
+
+	// resyncReplicas reports the outcomes of the last resync pass.
+	resyncReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "replizieren_resync_replicas",
+			Help: "Number of replicas per outcome in the last resync pass",
+		},
+		[]string{"outcome"},
+	)
+
+	// resyncDurationSeconds reports the duration of the last resync pass.
+	resyncDurationSeconds = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "replizieren_resync_duration_seconds",
+			Help: "Duration of the last resync pass in seconds",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		replicaDriftTotal,
+		replicaSyncErrorsTotal,
+		rolloutsTotal,
+		rolloutDurationSeconds,
+		resyncRunsTotal,
+		resyncReplicas,
+		resyncDurationSeconds,
+	)
+}
+
+// recordResync exports the summary of a resync pass.
+func recordResync(summary ResyncSummary, duration time.Duration) {
+	resyncRunsTotal.Inc()
+	resyncDurationSeconds.Set(duration.Seconds())
+	resyncReplicas.WithLabelValues("created").Set(float64(summary.Created))
+	resyncReplicas.WithLabelValues("updated").Set(float64(summary.Updated))
+	resyncReplicas.WithLabelValues("restored").Set(float64(summary.Restored))
+	resyncReplicas.WithLabelValues("unchanged").Set(float64(summary.Unchanged))
+	resyncReplicas.WithLabelValues("conflict").Set(float64(summary.Conflicts))
+	resyncReplicas.WithLabelValues("pending").Set(float64(summary.Pending))
+	resyncReplicas.WithLabelValues("failed").Set(float64(summary.Failed))
+}
@@ -18,11 +18,13 @@ package controller
 
 import (
 	"context"
+	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -30,16 +32,25 @@ import (
 )
 
 // NamespaceReconciler reconciles a Namespace object to trigger replication
-// of secrets and configmaps that have replicate-all enabled
+// of secrets and configmaps that target the namespace
 type NamespaceReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	Pause  *Pause
+	DryRun bool
+	// Recorder records replication and pruning as events on the source.
+	Recorder events.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
-
-// Reconcile handles namespace creation events and replicates secrets/configmaps
-// that have replicate-all annotation to the new namespace.
+// +kubebuilder:rbac:groups=replication.replizieren.dev,resources=replicationpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=replication.replizieren.dev,resources=replications,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+
+// Reconcile handles namespace creation and metadata changes. It replicates
+// secrets/configmaps whose namespace list, glob patterns, selectors or
+// replicate-all setting match the namespace, and removes replicas of sources
+// that no longer select it.
 func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
@@ -63,88 +74,132 @@ func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	logger.Info("New namespace detected, checking for resources to replicate", "namespace", namespace.Name)
-
-	// Replicate secrets with replicate-all annotation
-	secrets, err := GetSecretsToReplicateAll(ctx, r.Client)
+	// Sources reconcile the backlog, including this namespace, when replication resumes
+	paused, message, err := r.Pause.GloballyPaused(ctx, r.Client)
 	if err != nil {
-		logger.Error(err, "Failed to list secrets for replication")
 		return ctrl.Result{}, err
 	}
-
-	for _, secret := range secrets {
-		if secret.Namespace == namespace.Name {
-			continue // Don't replicate to source namespace
-		}
-		if err := r.replicateSecret(ctx, &secret, namespace.Name); err != nil {
-			logger.Error(err, "Failed to replicate secret", "secret", secret.Name, "from", secret.Namespace, "to", namespace.Name)
-			continue
-		}
-		logger.Info("Replicated secret to new namespace", "secret", secret.Name, "from", secret.Namespace, "to", namespace.Name)
+	if paused {
+		logger.Info("Replication paused, skipping", "namespace", namespace.Name, "reason", message)
+		return ctrl.Result{}, nil
 	}
 
-	// Replicate configmaps with replicate-all annotation
-	configmaps, err := GetConfigMapsToReplicateAll(ctx, r.Client)
+	logger.Info("Namespace created or changed, checking for resources to replicate", "namespace", namespace.Name)
+
+	sources, err := listSources(ctx, r.Client)
 	if err != nil {
-		logger.Error(err, "Failed to list configmaps for replication")
+		logger.Error(err, "Failed to list sources for replication")
 		return ctrl.Result{}, err
 	}
 
-	for _, cm := range configmaps {
-		if cm.Namespace == namespace.Name {
-			continue // Don't replicate to source namespace
+	// Failures are collected, so the namespace is retried with backoff
+	var errs []error
+	for _, source := range sources {
+		if source.GetNamespace() == namespace.Name || IsPaused(source) {
+			continue // Don't replicate to source namespace or paused sources
+		}
+		kind := SourceKindOf(source)
+		config, err := ResolveReplicationConfig(ctx, r.Client, source)
+		if err != nil {
+			logger.Error(err, "Failed to resolve replication config", "kind", kind, "name", source.GetName(), "namespace", source.GetNamespace())
+			errs = append(errs, err)
+			continue
 		}
-		if err := r.replicateConfigMap(ctx, &cm, namespace.Name); err != nil {
-			logger.Error(err, "Failed to replicate configmap", "configmap", cm.Name, "from", cm.Namespace, "to", namespace.Name)
+		if !config.MatchesNamespace(&namespace, source.GetNamespace()) {
 			continue
 		}
-		logger.Info("Replicated configmap to new namespace", "configmap", cm.Name, "from", cm.Namespace, "to", namespace.Name)
+		// Existing replicas are updated by the source, which holds updates
+		// for its waves and maintenance windows
+		exists, err := replicaExists(ctx, r.Client, source, namespace.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if exists {
+			continue
+		}
+		writer := writerFor(r.Client, r.Recorder, source, r.DryRun)
+		outcome, err := ReplicateObject(ctx, writer, source, namespace.Name, config)
+		recordReplication(r.Recorder, source, namespace.Name, outcome, err, r.DryRun || IsDryRun(source))
+		if err != nil {
+			logger.Error(err, "Failed to replicate", "kind", kind, "name", source.GetName(), "from", source.GetNamespace(), "to", namespace.Name)
+			replicaSyncErrorsTotal.WithLabelValues(string(kind), namespace.Name).Inc()
+			errs = append(errs, fmt.Errorf("%s %s/%s: %w", kind, source.GetNamespace(), source.GetName(), err))
+			continue
+		}
+		logger.Info("Replicated to new namespace", "kind", kind, "name", source.GetName(), "from", source.GetNamespace(), "to", namespace.Name)
 	}
 
-	return ctrl.Result{}, nil
-}
-
-func (r *NamespaceReconciler) replicateSecret(ctx context.Context, original *corev1.Secret, namespace string) error {
-	clone := original.DeepCopy()
-	clone.Namespace = namespace
-	clone.ResourceVersion = ""
-	clone.UID = ""
-
-	existing := &corev1.Secret{}
-	err := r.Get(ctx, types.NamespacedName{Name: clone.Name, Namespace: namespace}, existing)
-	if err != nil && errors.IsNotFound(err) {
-		return r.Create(ctx, clone)
-	} else if err != nil {
-		return err
+	if err := r.pruneReplicas(ctx, &namespace); err != nil {
+		errs = append(errs, err)
 	}
 
-	clone.ResourceVersion = existing.ResourceVersion
-	return r.Update(ctx, clone)
+	return ctrl.Result{}, kerrors.NewAggregate(errs)
 }
 
-func (r *NamespaceReconciler) replicateConfigMap(ctx context.Context, original *corev1.ConfigMap, namespace string) error {
-	clone := original.DeepCopy()
-	clone.Namespace = namespace
-	clone.ResourceVersion = ""
-	clone.UID = ""
-
-	existing := &corev1.ConfigMap{}
-	err := r.Get(ctx, types.NamespacedName{Name: clone.Name, Namespace: namespace}, existing)
-	if err != nil && errors.IsNotFound(err) {
-		return r.Create(ctx, clone)
-	} else if err != nil {
+// pruneReplicas deletes replicas in the namespace whose source still
+// replicates, but no longer selects the namespace, e.g. because a label used by
+// a namespace selector was removed. Replicas of sources that stopped
+// replicating altogether are left in place.
+func (r *NamespaceReconciler) pruneReplicas(ctx context.Context, namespace *corev1.Namespace) error {
+	logger := log.FromContext(ctx)
+
+	replicas, err := listReplicas(ctx, r.Client, namespace.Name)
+	if err != nil {
 		return err
 	}
 
-	clone.ResourceVersion = existing.ResourceVersion
-	return r.Update(ctx, clone)
+	var errs []error
+	for _, replica := range replicas {
+		ref, ok := SourceOf(replica)
+		if !ok {
+			continue
+		}
+		source := newSourceObject(SourceKindOf(replica))
+		if err := r.Get(ctx, ref, source); err != nil {
+			if !errors.IsNotFound(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if IsPaused(source) {
+			continue
+		}
+
+		config, err := ResolveReplicationConfig(ctx, r.Client, source)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if config.SkipReplication || config.MatchesNamespace(namespace, source.GetNamespace()) {
+			continue
+		}
+
+		dryRun := r.DryRun || IsDryRun(source)
+		if err := writerFor(r.Client, r.Recorder, source, dryRun).Delete(ctx, replica); client.IgnoreNotFound(err) != nil {
+			errs = append(errs, err)
+			continue
+		}
+		logger.Info("Removed replica from namespace that is no longer selected", "kind", SourceKindOf(replica),
+			"name", replica.GetName(), "namespace", namespace.Name, "source", ref.String())
+		if r.Recorder != nil && !dryRun {
+			r.Recorder.Eventf(source, nil, corev1.EventTypeNormal, reasonPruned, "Delete",
+				"Removed replica from namespace %s, which is no longer selected", namespace.Name)
+		}
+	}
+	return kerrors.NewAggregate(errs)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Namespace{}).
-		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		// Namespaces do not bump their generation on metadata changes
+		WithEventFilter(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		)).
 		Named("namespace").
 		Complete(r)
 }
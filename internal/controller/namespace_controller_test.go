@@ -24,6 +24,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
 )
 
 var _ = Describe("Namespace Controller", func() {
@@ -178,6 +180,80 @@ var _ = Describe("Namespace Controller", func() {
 			return k8sClient.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: targetNs.Name}, &corev1.Secret{})
 		}, timeout, interval).Should(Succeed())
 	})
+
+	// Test 6: Explicitly named and glob targets created after the source
+	It("should replicate to explicitly named and glob matched namespaces created later", func() {
+		srcNs := createTestNamespace("ns-src-later")
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "later-configmap",
+				Namespace: srcNs.Name,
+				Annotations: map[string]string{
+					ReplicateKey: "ns-later-named, ns-later-glob-*",
+				},
+			},
+			Data: map[string]string{"config": "data"},
+		}
+		Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+		// Create the target namespaces after the source
+		namedNs := createTestNamespace("ns-later-named")
+		globNs := createTestNamespace("ns-later-glob-a")
+
+		for _, ns := range []string{namedNs.Name, globNs.Name} {
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: cm.Name, Namespace: ns}, &corev1.ConfigMap{})
+			}, timeout, interval).Should(Succeed())
+		}
+	})
+
+	// Test 7: Labelling an existing namespace adds and removes replicas
+	It("should follow namespace label changes", func() {
+		srcNs := createTestNamespace("ns-src-labels")
+		targetNs := createTestNamespace("ns-tgt-labels")
+
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "label-configmap", Namespace: srcNs.Name},
+			Data:       map[string]string{"config": "data"},
+		}
+		Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+		policy := &replicationv1alpha1.ReplicationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "label-policy"},
+			Spec: replicationv1alpha1.ReplicationPolicySpec{
+				Sources: replicationv1alpha1.SourceSelector{
+					Namespace: srcNs.Name,
+					Kinds:     []replicationv1alpha1.SourceKind{replicationv1alpha1.SourceKindConfigMap},
+					Names:     []string{cm.Name},
+				},
+				Targets: replicationv1alpha1.TargetSelector{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"label-test": "enabled"}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+
+		key := types.NamespacedName{Name: cm.Name, Namespace: targetNs.Name}
+		Consistently(func() error {
+			return k8sClient.Get(ctx, key, &corev1.ConfigMap{})
+		}, 3*time.Second, interval).ShouldNot(Succeed())
+
+		// Adding the selector label delivers the configmap
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: targetNs.Name}, targetNs)).To(Succeed())
+		targetNs.Labels = map[string]string{"label-test": "enabled"}
+		Expect(k8sClient.Update(ctx, targetNs)).To(Succeed())
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, &corev1.ConfigMap{})
+		}, timeout, interval).Should(Succeed())
+
+		// Removing it again removes the replica
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: targetNs.Name}, targetNs)).To(Succeed())
+		targetNs.Labels = nil
+		Expect(k8sClient.Update(ctx, targetNs)).To(Succeed())
+		Eventually(func() error {
+			return k8sClient.Get(ctx, key, &corev1.ConfigMap{})
+		}, timeout, interval).ShouldNot(Succeed())
+	})
 })
 
 func createTestNamespace(name string) *corev1.Namespace {
@@ -0,0 +1,120 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// PausedKey pauses replication of a single source when set to "true".
+const PausedKey = "replizieren.dev/paused"
+
+// pausedDataKey is the key of the control ConfigMap that pauses replication
+// cluster-wide when set to "true".
+const pausedDataKey = "paused"
+
+// Event reasons for pausing
+const (
+	reasonPaused = "ReplicationPaused"
+)
+
+// Pause is the cluster-wide kill switch. Replication is paused if Paused is
+// set or the control ConfigMap contains paused: "true". A nil Pause never
+// pauses.
+type Pause struct {
+	// Paused pauses replication regardless of the control ConfigMap.
+	Paused bool
+	// ConfigMap is the control ConfigMap that pauses replication at runtime.
+	// An empty name disables the ConfigMap.
+	ConfigMap types.NamespacedName
+}
+
+// GloballyPaused returns true and a message if replication is paused
+// cluster-wide.
+func (p *Pause) GloballyPaused(ctx context.Context, c client.Client) (bool, string, error) {
+	if p == nil {
+		return false, "", nil
+	}
+	if p.Paused {
+		return true, "Replication is paused cluster-wide by the --paused flag", nil
+	}
+	if p.ConfigMap.Name == "" {
+		return false, "", nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, p.ConfigMap, &cm); err != nil {
+		if errors.IsNotFound(err) {
+			return false, "", nil
+		}
+		return false, "", err
+	}
+	if cm.Data[pausedDataKey] == "true" {
+		return true, fmt.Sprintf("Replication is paused cluster-wide by ConfigMap %s", p.ConfigMap), nil
+	}
+	return false, "", nil
+}
+
+// isControlConfigMap returns true if the object is the control ConfigMap.
+func (p *Pause) isControlConfigMap(obj client.Object) bool {
+	return p != nil && p.ConfigMap.Name != "" && client.ObjectKeyFromObject(obj) == p.ConfigMap
+}
+
+// controlConfigMapPredicate selects the control ConfigMap.
+func (p *Pause) controlConfigMapPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(p.isControlConfigMap)
+}
+
+// IsPaused returns true if replication of the source is paused by annotation.
+func IsPaused(source client.Object) bool {
+	return source.GetAnnotations()[PausedKey] == "true"
+}
+
+// requestsForSources returns reconcile requests for all sources of the given
+// object type. It is used to reconcile the backlog when replication resumes.
+func requestsForSources(ctx context.Context, c client.Client, list client.ObjectList) []reconcile.Request {
+	if err := c.List(ctx, list); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list sources")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	switch l := list.(type) {
+	case *corev1.SecretList:
+		for i := range l.Items {
+			if !IsReplica(&l.Items[i]) && !IsRevision(&l.Items[i]) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&l.Items[i])})
+			}
+		}
+	case *corev1.ConfigMapList:
+		for i := range l.Items {
+			if !IsReplica(&l.Items[i]) && !IsRevision(&l.Items[i]) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&l.Items[i])})
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestGloballyPaused(t *testing.T) {
+	ctx := context.Background()
+	control := types.NamespacedName{Namespace: "replizieren-system", Name: "replizieren-control"}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+
+	var nilPause *Pause
+	if paused, _, _ := nilPause.GloballyPaused(ctx, c); paused {
+		t.Error("expected nil pause to never pause")
+	}
+	if paused, _, _ := (&Pause{Paused: true}).GloballyPaused(ctx, c); !paused {
+		t.Error("expected flag to pause")
+	}
+
+	pause := &Pause{ConfigMap: control}
+	if paused, _, err := pause.GloballyPaused(ctx, c); paused || err != nil {
+		t.Errorf("expected missing control ConfigMap to not pause, got %v, %v", paused, err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: control.Name, Namespace: control.Namespace},
+		Data:       map[string]string{pausedDataKey: "true"},
+	}
+	if err := c.Create(ctx, cm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paused, _, _ := pause.GloballyPaused(ctx, c); !paused {
+		t.Error("expected control ConfigMap to pause")
+	}
+}
+
+func TestSyncSource_Paused(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey: "target-ns",
+				PausedKey:    "true",
+			},
+		},
+		Data: map[string][]byte{"key": []byte("shared")},
+	}
+	replication := &replicationv1alpha1.Replication{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Spec: replicationv1alpha1.ReplicationSpec{
+			Source:  replicationv1alpha1.SourceReference{Kind: replicationv1alpha1.SourceKindSecret, Name: "creds"},
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"target-ns"}},
+		},
+	}
+	f := newSyncFixture(t, source, replication, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "target-ns"}})
+
+	if _, err := f.syncer.sync(f.ctx, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.exists(&corev1.Secret{}, "target-ns", "creds") {
+		t.Error("expected paused source to not be replicated")
+	}
+	f.get(replication)
+	if !meta.IsStatusConditionTrue(replication.Status.Conditions, replicationv1alpha1.ConditionPaused) {
+		t.Error("expected Paused condition on the Replication")
+	}
+
+	delete(source.Annotations, PausedKey)
+	if _, err := f.syncer.sync(f.ctx, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.exists(&corev1.Secret{}, "target-ns", "creds") {
+		t.Error("expected source to be replicated after resuming")
+	}
+	f.get(replication)
+	if meta.FindStatusCondition(replication.Status.Conditions, replicationv1alpha1.ConditionPaused) != nil {
+		t.Error("expected Paused condition to be removed after resuming")
+	}
+}
+
+func TestRequestsForSources(t *testing.T) {
+	secret := func(name string, labels map[string]string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps", Labels: labels}}
+	}
+	c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(
+		secret("source", nil),
+		secret("replica", map[string]string{ReplicaLabel: "true"}),
+		secret("revision", map[string]string{RevisionLabel: "1"}),
+	).Build()
+
+	requests := requestsForSources(context.Background(), c, &corev1.SecretList{})
+	if len(requests) != 1 || requests[0].Name != "source" {
+		t.Errorf("expected only the source to be enqueued, got %v", requests)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// selectorOrNothing converts a label selector, treating invalid selectors as
+// matching nothing and a nil selector as matching everything.
+func selectorOrNothing(selector *metav1.LabelSelector) labels.Selector {
+	if selector == nil {
+		return labels.Everything()
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return labels.Nothing()
+	}
+	return s
+}
+
+// PolicyMatchesSource returns true if the policy selects the source object.
+func PolicyMatchesSource(policy *replicationv1alpha1.ReplicationPolicy, obj client.Object) bool {
+	sources := policy.Spec.Sources
+	if sources.Namespace != obj.GetNamespace() {
+		return false
+	}
+	if len(sources.Kinds) > 0 && !slices.Contains(sources.Kinds, SourceKindOf(obj)) {
+		return false
+	}
+	if len(sources.Names) > 0 && !slices.Contains(sources.Names, obj.GetName()) {
+		return false
+	}
+	return selectorOrNothing(sources.Selector).Matches(labels.Set(obj.GetLabels()))
+}
+
+// ResolveReplicationConfig combines the replication annotations of a source
+// with all Replications and ReplicationPolicies that select it.
+func ResolveReplicationConfig(ctx context.Context, c client.Client, obj client.Object) (ReplicationConfig, error) {
+	replications, err := ReplicationsForSource(ctx, c, obj)
+	if err != nil {
+		return ReplicationConfig{}, err
+	}
+
+	var policies replicationv1alpha1.ReplicationPolicyList
+	if err := c.List(ctx, &policies); err != nil {
+		return ReplicationConfig{}, err
+	}
+
+	var matching []replicationv1alpha1.ReplicationPolicy
+	for _, policy := range policies.Items {
+		if PolicyMatchesSource(&policy, obj) {
+			matching = append(matching, policy)
+		}
+	}
+
+	rules := append(replicationRules(replications), policyRules(matching)...)
+	return mergeRules(obj.GetAnnotations(), obj.GetNamespace(), rules), nil
+}
+
+// replicationRule is the part of a Replication or ReplicationPolicy that
+// contributes to the replication config of a source.
+type replicationRule struct {
+	targets replicationv1alpha1.TargetSelector
+	options replicationv1alpha1.ReplicationOptions
+}
+
+// replicationRules returns the rules of the given Replications sorted by name.
+// Replications are created by tenants, so they only target namespaces by name
+// or pattern, never system or protected namespaces, and always skip unmanaged
+// objects. The webhook rejects everything else.
+func replicationRules(replications []replicationv1alpha1.Replication) []replicationRule {
+	sorted := slices.Clone(replications)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	rules := make([]replicationRule, 0, len(sorted))
+	for _, replication := range sorted {
+		targets := *replication.Spec.Targets.DeepCopy()
+		targets.Namespaces = slices.DeleteFunc(targets.Namespaces, IsProtectedNamespace)
+		targets.AllNamespaces = false
+		targets.NamespaceSelector = nil
+		options := replication.Spec.Options
+		options.ConflictPolicy = replicationv1alpha1.ConflictPolicySkip
+		rules = append(rules, replicationRule{targets: targets, options: options})
+	}
+	return rules
+}
+
+// policyRules returns the rules of the given policies sorted by name.
+func policyRules(policies []replicationv1alpha1.ReplicationPolicy) []replicationRule {
+	sorted := slices.Clone(policies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	rules := make([]replicationRule, 0, len(sorted))
+	for _, policy := range sorted {
+		rules = append(rules, replicationRule{targets: policy.Spec.Targets, options: policy.Spec.Options})
+	}
+	return rules
+}
+
+// MergePolicies builds the replication config of a source from its annotations
+// and the policies that select it.
+//
+// Precedence rules:
+//   - Targets are the union of the annotation targets and all policy targets
+//   - Policy exclusions always apply, even to namespaces named in annotations
+//   - Options set through annotations win over policy options
+//   - Between policies, options of the policy whose name sorts first win
+func MergePolicies(
+	annotations map[string]string,
+	sourceNamespace string,
+	policies []replicationv1alpha1.ReplicationPolicy,
+) ReplicationConfig {
+	return mergeRules(annotations, sourceNamespace, policyRules(policies))
+}
+
+// mergeRules applies rules on top of the annotation config in order. Targets
+// and exclusions of all rules are combined, options of earlier rules win.
+func mergeRules(annotations map[string]string, sourceNamespace string, rules []replicationRule) ReplicationConfig {
+	config := ParseReplicationConfig(annotations, sourceNamespace)
+	if len(rules) == 0 {
+		return config
+	}
+
+	_, rolloutSet := annotations[RolloutOnUpdateKey]
+	for _, rule := range rules {
+		targets := rule.targets
+		for _, ns := range targets.Namespaces {
+			if ns != sourceNamespace && !slices.Contains(config.TargetNamespaces, ns) {
+				config.TargetNamespaces = append(config.TargetNamespaces, ns)
+			}
+		}
+		if targets.AllNamespaces {
+			config.ReplicateAll = true
+		}
+		if targets.NamespaceSelector != nil {
+			config.NamespaceSelectors = append(config.NamespaceSelectors, selectorOrNothing(targets.NamespaceSelector))
+		}
+		config.ExcludeNamespaces = append(config.ExcludeNamespaces, targets.ExcludeNamespaces...)
+
+		options := rule.options
+		if !rolloutSet && options.RolloutOnUpdate != nil {
+			config.RolloutOnUpdate = *options.RolloutOnUpdate
+			rolloutSet = true
+		}
+		if len(config.Keys) == 0 {
+			config.Keys = options.Keys
+		}
+		if config.ConflictPolicy == "" {
+			config.ConflictPolicy = options.ConflictPolicy
+		}
+		if len(config.Waves) == 0 {
+			config.Waves = wavesFromAPI(options.Waves)
+		}
+	}
+
+	config.SkipReplication = !config.ReplicateAll &&
+		len(config.TargetNamespaces) == 0 &&
+		len(config.NamespaceSelectors) == 0
+	return config
+}
+
+// requestsForPolicy returns reconcile requests for all sources of the given
+// kind that are selected by the policy.
+func requestsForPolicy(
+	ctx context.Context,
+	c client.Client,
+	policy *replicationv1alpha1.ReplicationPolicy,
+	kind replicationv1alpha1.SourceKind,
+) []reconcile.Request {
+	var objects []client.Object
+	switch kind {
+	case replicationv1alpha1.SourceKindSecret:
+		var list corev1.SecretList
+		if err := c.List(ctx, &list, client.InNamespace(policy.Spec.Sources.Namespace)); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list secrets for policy", "policy", policy.Name)
+			return nil
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	case replicationv1alpha1.SourceKindConfigMap:
+		var list corev1.ConfigMapList
+		if err := c.List(ctx, &list, client.InNamespace(policy.Spec.Sources.Namespace)); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list configmaps for policy", "policy", policy.Name)
+			return nil
+		}
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	}
+
+	var requests []reconcile.Request
+	for _, obj := range objects {
+		if !IsReplica(obj) && PolicyMatchesSource(policy, obj) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+			})
+		}
+	}
+	return requests
+}
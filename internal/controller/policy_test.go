@@ -0,0 +1,267 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func newPolicy(name string, spec replicationv1alpha1.ReplicationPolicySpec) replicationv1alpha1.ReplicationPolicy {
+	return replicationv1alpha1.ReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       spec,
+	}
+}
+
+func TestPolicyMatchesSource_Namespace(t *testing.T) {
+	policy := newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+		Sources: replicationv1alpha1.SourceSelector{Namespace: "platform"},
+	})
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "platform"}}
+	if !PolicyMatchesSource(&policy, secret) {
+		t.Error("expected policy to match secret in source namespace")
+	}
+	secret.Namespace = "other"
+	if PolicyMatchesSource(&policy, secret) {
+		t.Error("expected policy to not match secret in another namespace")
+	}
+}
+
+func TestPolicyMatchesSource_KindsNamesAndSelector(t *testing.T) {
+	policy := newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+		Sources: replicationv1alpha1.SourceSelector{
+			Namespace: "platform",
+			Kinds:     []replicationv1alpha1.SourceKind{replicationv1alpha1.SourceKindConfigMap},
+			Names:     []string{"app-config"},
+			Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"shared": "true"}},
+		},
+	})
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      "app-config",
+		Namespace: "platform",
+		Labels:    map[string]string{"shared": "true"},
+	}}
+	if !PolicyMatchesSource(&policy, cm) {
+		t.Error("expected policy to match configmap")
+	}
+
+	secret := &corev1.Secret{ObjectMeta: cm.ObjectMeta}
+	if PolicyMatchesSource(&policy, secret) {
+		t.Error("expected policy to not match a secret when kinds is ConfigMap")
+	}
+
+	cm.Labels = nil
+	if PolicyMatchesSource(&policy, cm) {
+		t.Error("expected policy to not match configmap without the selected label")
+	}
+}
+
+func TestMergePolicies_NoPolicies(t *testing.T) {
+	annotations := map[string]string{ReplicateKey: "ns1"}
+	config := MergePolicies(annotations, "source-ns", nil)
+	if len(config.TargetNamespaces) != 1 || config.TargetNamespaces[0] != "ns1" {
+		t.Errorf("expected annotation targets to be kept, got %v", config.TargetNamespaces)
+	}
+}
+
+func TestMergePolicies_UnionOfTargets(t *testing.T) {
+	annotations := map[string]string{ReplicateKey: "ns1"}
+	policies := []replicationv1alpha1.ReplicationPolicy{
+		newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"ns1", "ns2", "source-ns"}},
+		}),
+	}
+	config := MergePolicies(annotations, "source-ns", policies)
+	if config.SkipReplication {
+		t.Error("expected SkipReplication to be false")
+	}
+	if len(config.TargetNamespaces) != 2 {
+		t.Errorf("expected targets ns1 and ns2, got %v", config.TargetNamespaces)
+	}
+}
+
+func TestMergePolicies_PolicyEnablesReplicationWithoutAnnotations(t *testing.T) {
+	policies := []replicationv1alpha1.ReplicationPolicy{
+		newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+			Targets: replicationv1alpha1.TargetSelector{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			},
+		}),
+	}
+	config := MergePolicies(nil, "source-ns", policies)
+	if config.SkipReplication {
+		t.Error("expected SkipReplication to be false when a policy selects namespaces")
+	}
+	if len(config.NamespaceSelectors) != 1 {
+		t.Errorf("expected one namespace selector, got %d", len(config.NamespaceSelectors))
+	}
+}
+
+func TestMergePolicies_AnnotationOptionsWin(t *testing.T) {
+	annotations := map[string]string{
+		ReplicateKey:       "ns1",
+		RolloutOnUpdateKey: "false",
+		KeysKey:            "username",
+	}
+	policies := []replicationv1alpha1.ReplicationPolicy{
+		newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+			Options: replicationv1alpha1.ReplicationOptions{
+				RolloutOnUpdate: pointerTo(true),
+				Keys:            []string{"password"},
+				ConflictPolicy:  replicationv1alpha1.ConflictPolicySkip,
+			},
+		}),
+	}
+	config := MergePolicies(annotations, "source-ns", policies)
+	if config.RolloutOnUpdate {
+		t.Error("expected annotation rollout-on-update to win over policy")
+	}
+	if len(config.Keys) != 1 || config.Keys[0] != "username" {
+		t.Errorf("expected annotation keys to win over policy, got %v", config.Keys)
+	}
+	if config.ConflictPolicy != replicationv1alpha1.ConflictPolicySkip {
+		t.Errorf("expected policy conflict policy when annotation is not set, got %q", config.ConflictPolicy)
+	}
+}
+
+func TestMergePolicies_FirstPolicyByNameWins(t *testing.T) {
+	policies := []replicationv1alpha1.ReplicationPolicy{
+		newPolicy("b", replicationv1alpha1.ReplicationPolicySpec{
+			Options: replicationv1alpha1.ReplicationOptions{RolloutOnUpdate: pointerTo(false)},
+		}),
+		newPolicy("a", replicationv1alpha1.ReplicationPolicySpec{
+			Options: replicationv1alpha1.ReplicationOptions{RolloutOnUpdate: pointerTo(true)},
+		}),
+	}
+	config := MergePolicies(nil, "source-ns", policies)
+	if !config.RolloutOnUpdate {
+		t.Error("expected options of policy 'a' to win")
+	}
+}
+
+func TestMergePolicies_ExclusionsApplyToAnnotationTargets(t *testing.T) {
+	annotations := map[string]string{ReplicateKey: "ns1,ns2"}
+	policies := []replicationv1alpha1.ReplicationPolicy{
+		newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+			Targets: replicationv1alpha1.TargetSelector{ExcludeNamespaces: []string{"ns2"}},
+		}),
+	}
+	config := MergePolicies(annotations, "source-ns", policies)
+	ns2 := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns2"}}
+	if config.MatchesNamespace(ns2, "source-ns") {
+		t.Error("expected excluded namespace to not match")
+	}
+}
+
+func TestMatchesNamespace_SelectorSkipsSystemNamespaces(t *testing.T) {
+	policies := []replicationv1alpha1.ReplicationPolicy{
+		newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+			Targets: replicationv1alpha1.TargetSelector{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			},
+		}),
+	}
+	config := MergePolicies(nil, "source-ns", policies)
+
+	prod := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"env": "prod"}}}
+	if !config.MatchesNamespace(prod, "source-ns") {
+		t.Error("expected labelled namespace to match")
+	}
+	dev := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev", Labels: map[string]string{"env": "dev"}}}
+	if config.MatchesNamespace(dev, "source-ns") {
+		t.Error("expected namespace with other label to not match")
+	}
+	system := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Labels: map[string]string{"env": "prod"}}}
+	if config.MatchesNamespace(system, "source-ns") {
+		t.Error("expected system namespace to not match a selector")
+	}
+}
+
+func TestMatchesNamespace_GlobPatterns(t *testing.T) {
+	config := ParseReplicationConfig(map[string]string{ReplicateKey: "team-*, env-[ab], kube-*"}, "source-ns")
+
+	for _, name := range []string{"team-a", "team-payments", "env-a"} {
+		if !config.MatchesNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, "source-ns") {
+			t.Errorf("expected %s to match", name)
+		}
+	}
+	for _, name := range []string{"team", "env-c", "kube-system"} {
+		if config.MatchesNamespace(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, "source-ns") {
+			t.Errorf("expected %s to not match", name)
+		}
+	}
+}
+
+func TestMatchesNamespace_ProtectedNamespaces(t *testing.T) {
+	ProtectedNamespaces["vault"] = true
+	defer delete(ProtectedNamespaces, "vault")
+	vault := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "vault"}}
+
+	annotated := ParseReplicationConfig(map[string]string{ReplicateKey: "vault, team-a"}, "source-ns")
+	if !slices.Equal(annotated.TargetNamespaces, []string{"team-a"}) || annotated.MatchesNamespace(vault, "source-ns") {
+		t.Errorf("expected annotations to not name a protected namespace, got %v", annotated.TargetNamespaces)
+	}
+	for _, annotations := range []map[string]string{{ReplicateKey: "va*"}, {ReplicateAllKey: "true"}} {
+		if ParseReplicationConfig(annotations, "source-ns").MatchesNamespace(vault, "source-ns") {
+			t.Errorf("expected %v to not match a protected namespace", annotations)
+		}
+	}
+
+	// Platform teams may still name it in a policy
+	policy := replicationv1alpha1.ReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "vault"},
+		Spec:       replicationv1alpha1.ReplicationPolicySpec{Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"vault"}}},
+	}
+	if !MergePolicies(nil, "source-ns", []replicationv1alpha1.ReplicationPolicy{policy}).MatchesNamespace(vault, "source-ns") {
+		t.Error("expected a policy to be able to name a protected namespace")
+	}
+}
+
+func TestResolveTargetNamespaces_GlobPatterns(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	).Build()
+
+	config := ParseReplicationConfig(map[string]string{ReplicateKey: "team-*, missing"}, "source-ns")
+	namespaces, err := ResolveTargetNamespaces(context.Background(), c, config, "source-ns")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(namespaces, []string{"team-a", "team-b"}) {
+		t.Errorf("expected existing namespaces matching the pattern, got %v", namespaces)
+	}
+
+	config = ParseReplicationConfig(map[string]string{ReplicateKey: "other, missing"}, "source-ns")
+	namespaces, err = ResolveTargetNamespaces(context.Background(), c, config, "source-ns")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(namespaces, []string{"other"}) {
+		t.Errorf("expected explicitly named namespaces that exist, got %v", namespaces)
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// referenceVisitor is called for every Secret and ConfigMap referenced by a
+// pod spec with the keys the reference consumes. Keys are nil if the reference
+// consumes all keys. Returning false stops the walk.
+type referenceVisitor func(kind replicationv1alpha1.SourceKind, name string, keys []string) bool
+
+// visitPodSpecReferences calls visit for every Secret and ConfigMap the pod
+// spec references: image pull secrets, volumes including projected and CSI
+// volumes, and env and envFrom of init, regular and ephemeral containers.
+// It returns false if visit stopped the walk.
+func visitPodSpecReferences(spec *corev1.PodSpec, visit referenceVisitor) bool {
+	secret := func(name string, keys []string) bool {
+		return name == "" || visit(replicationv1alpha1.SourceKindSecret, name, keys)
+	}
+	configMap := func(name string, keys []string) bool {
+		return name == "" || visit(replicationv1alpha1.SourceKindConfigMap, name, keys)
+	}
+
+	for _, ref := range spec.ImagePullSecrets {
+		if !secret(ref.Name, nil) {
+			return false
+		}
+	}
+	for i := range spec.Volumes {
+		if !visitVolumeReferences(&spec.Volumes[i].VolumeSource, secret, configMap) {
+			return false
+		}
+	}
+	for i := range spec.InitContainers {
+		c := &spec.InitContainers[i]
+		if !visitEnvReferences(c.Env, c.EnvFrom, secret, configMap) {
+			return false
+		}
+	}
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		if !visitEnvReferences(c.Env, c.EnvFrom, secret, configMap) {
+			return false
+		}
+	}
+	for i := range spec.EphemeralContainers {
+		c := &spec.EphemeralContainers[i]
+		if !visitEnvReferences(c.Env, c.EnvFrom, secret, configMap) {
+			return false
+		}
+	}
+	return true
+}
+
+// visitVolumeReferences visits the Secrets and ConfigMaps of a volume.
+func visitVolumeReferences(vol *corev1.VolumeSource, secret, configMap func(string, []string) bool) bool {
+	switch {
+	case vol.Secret != nil:
+		return secret(vol.Secret.SecretName, itemKeys(vol.Secret.Items))
+	case vol.ConfigMap != nil:
+		return configMap(vol.ConfigMap.Name, itemKeys(vol.ConfigMap.Items))
+	case vol.Projected != nil:
+		for _, source := range vol.Projected.Sources {
+			if source.Secret != nil && !secret(source.Secret.Name, itemKeys(source.Secret.Items)) {
+				return false
+			}
+			if source.ConfigMap != nil && !configMap(source.ConfigMap.Name, itemKeys(source.ConfigMap.Items)) {
+				return false
+			}
+		}
+	case vol.CSI != nil:
+		if vol.CSI.NodePublishSecretRef != nil {
+			return secret(vol.CSI.NodePublishSecretRef.Name, nil)
+		}
+	case vol.AzureFile != nil:
+		return secret(vol.AzureFile.SecretName, nil)
+	case vol.CephFS != nil && vol.CephFS.SecretRef != nil:
+		return secret(vol.CephFS.SecretRef.Name, nil)
+	case vol.Cinder != nil && vol.Cinder.SecretRef != nil:
+		return secret(vol.Cinder.SecretRef.Name, nil)
+	case vol.FlexVolume != nil && vol.FlexVolume.SecretRef != nil:
+		return secret(vol.FlexVolume.SecretRef.Name, nil)
+	case vol.ISCSI != nil && vol.ISCSI.SecretRef != nil:
+		return secret(vol.ISCSI.SecretRef.Name, nil)
+	case vol.RBD != nil && vol.RBD.SecretRef != nil:
+		return secret(vol.RBD.SecretRef.Name, nil)
+	case vol.ScaleIO != nil && vol.ScaleIO.SecretRef != nil:
+		return secret(vol.ScaleIO.SecretRef.Name, nil)
+	case vol.StorageOS != nil && vol.StorageOS.SecretRef != nil:
+		return secret(vol.StorageOS.SecretRef.Name, nil)
+	}
+	return true
+}
+
+// itemKeys returns the keys projected by a volume, or nil if the volume
+// projects all keys.
+func itemKeys(items []corev1.KeyToPath) []string {
+	if len(items) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+// visitEnvReferences visits the Secrets and ConfigMaps of a container's
+// environment.
+func visitEnvReferences(env []corev1.EnvVar, envFrom []corev1.EnvFromSource, secret, configMap func(string, []string) bool) bool {
+	for _, source := range envFrom {
+		if source.SecretRef != nil && !secret(source.SecretRef.Name, nil) {
+			return false
+		}
+		if source.ConfigMapRef != nil && !configMap(source.ConfigMapRef.Name, nil) {
+			return false
+		}
+	}
+	for _, e := range env {
+		if e.ValueFrom == nil {
+			continue
+		}
+		if ref := e.ValueFrom.SecretKeyRef; ref != nil && !secret(ref.Name, []string{ref.Key}) {
+			return false
+		}
+		if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil && !configMap(ref.Name, []string{ref.Key}) {
+			return false
+		}
+	}
+	return true
+}
+
+// ConsumedKeys returns the keys of the named object a pod spec consumes. All
+// is true if at least one reference consumes all keys, used is false if the
+// pod spec does not reference the object at all.
+func ConsumedKeys(spec *corev1.PodSpec, kind replicationv1alpha1.SourceKind, name string) (keys []string, all, used bool) {
+	visitPodSpecReferences(spec, func(k replicationv1alpha1.SourceKind, n string, refKeys []string) bool {
+		if k != kind || n != name {
+			return true
+		}
+		used = true
+		if refKeys == nil {
+			all = true
+			return false
+		}
+		for _, key := range refKeys {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+		return true
+	})
+	if all {
+		return nil, true, true
+	}
+	slices.Sort(keys)
+	return keys, false, used
+}
+
+// isPodSpecReferencing checks if a pod spec references the named object.
+func isPodSpecReferencing(spec *corev1.PodSpec, kind replicationv1alpha1.SourceKind, name string) bool {
+	return !visitPodSpecReferences(spec, func(k replicationv1alpha1.SourceKind, n string, _ []string) bool {
+		return k != kind || n != name
+	})
+}
+
+// IsPodSpecUsingSecret checks if a pod spec uses the named secret
+func IsPodSpecUsingSecret(spec *corev1.PodSpec, secretName string) bool {
+	return isPodSpecReferencing(spec, replicationv1alpha1.SourceKindSecret, secretName)
+}
+
+// IsPodSpecUsingConfigMap checks if a pod spec uses the named configmap
+func IsPodSpecUsingConfigMap(spec *corev1.PodSpec, cmName string) bool {
+	return isPodSpecReferencing(spec, replicationv1alpha1.SourceKindConfigMap, cmName)
+}
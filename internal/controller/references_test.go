@@ -0,0 +1,248 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestPodSpecReferences(t *testing.T) {
+	ref := corev1.LocalObjectReference{Name: "ref"}
+	other := corev1.LocalObjectReference{Name: "other"}
+	container := func(c corev1.Container) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{c}}
+	}
+	volume := func(source corev1.VolumeSource) corev1.PodSpec {
+		return corev1.PodSpec{Volumes: []corev1.Volume{{Name: "vol", VolumeSource: source}}}
+	}
+
+	tests := []struct {
+		name      string
+		spec      corev1.PodSpec
+		secret    bool
+		configMap bool
+	}{
+		{
+			name: "empty pod spec",
+			spec: corev1.PodSpec{},
+		},
+		{
+			name:   "secret volume",
+			spec:   volume(corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "ref"}}),
+			secret: true,
+		},
+		{
+			name:      "configmap volume",
+			spec:      volume(corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: ref}}),
+			configMap: true,
+		},
+		{
+			name: "volume of another secret",
+			spec: volume(corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "other"}}),
+		},
+		{
+			name: "projected volume",
+			spec: volume(corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+				{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token"}},
+				{Secret: &corev1.SecretProjection{LocalObjectReference: ref}},
+				{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: ref}},
+			}}}),
+			secret:    true,
+			configMap: true,
+		},
+		{
+			name: "projected volume of other objects",
+			spec: volume(corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+				{Secret: &corev1.SecretProjection{LocalObjectReference: other}},
+				{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: other}},
+			}}}),
+		},
+		{
+			name: "csi node publish secret",
+			spec: volume(corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{
+				Driver:               "secrets-store.csi.k8s.io",
+				NodePublishSecretRef: &ref,
+			}}),
+			secret: true,
+		},
+		{
+			name: "csi volume without secret",
+			spec: volume(corev1.VolumeSource{CSI: &corev1.CSIVolumeSource{Driver: "secrets-store.csi.k8s.io"}}),
+		},
+		{
+			name:   "azure file secret",
+			spec:   volume(corev1.VolumeSource{AzureFile: &corev1.AzureFileVolumeSource{SecretName: "ref", ShareName: "share"}}),
+			secret: true,
+		},
+		{
+			name:   "rbd secret",
+			spec:   volume(corev1.VolumeSource{RBD: &corev1.RBDVolumeSource{RBDImage: "image", SecretRef: &ref}}),
+			secret: true,
+		},
+		{
+			name: "image pull secret",
+			spec: corev1.PodSpec{
+				ImagePullSecrets: []corev1.LocalObjectReference{other, ref},
+			},
+			secret: true,
+		},
+		{
+			name:   "envFrom secret",
+			spec:   container(corev1.Container{EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: ref}}}}),
+			secret: true,
+		},
+		{
+			name:      "envFrom configmap",
+			spec:      container(corev1.Container{EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: ref}}}}),
+			configMap: true,
+		},
+		{
+			name: "env secretKeyRef",
+			spec: container(corev1.Container{Env: []corev1.EnvVar{
+				{Name: "PLAIN", Value: "value"},
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: ref, Key: "password"}}},
+			}}),
+			secret: true,
+		},
+		{
+			name: "env configMapKeyRef",
+			spec: container(corev1.Container{Env: []corev1.EnvVar{
+				{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: ref, Key: "level"}}},
+			}}),
+			configMap: true,
+		},
+		{
+			name: "env fieldRef",
+			spec: container(corev1.Container{Env: []corev1.EnvVar{
+				{Name: "POD", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+			}}),
+		},
+		{
+			name: "init container",
+			spec: corev1.PodSpec{InitContainers: []corev1.Container{{
+				EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: ref}}},
+			}}},
+			secret: true,
+		},
+		{
+			name: "ephemeral container",
+			spec: corev1.PodSpec{EphemeralContainers: []corev1.EphemeralContainer{{
+				EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+					Env: []corev1.EnvVar{{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: ref}}}},
+				},
+			}}},
+			configMap: true,
+		},
+		{
+			name: "references in a later container",
+			spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "sidecar"},
+				{Name: "app", EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: ref}}}},
+			}},
+			secret: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPodSpecUsingSecret(&tt.spec, "ref"); got != tt.secret {
+				t.Errorf("IsPodSpecUsingSecret() = %v, want %v", got, tt.secret)
+			}
+			if got := IsPodSpecUsingConfigMap(&tt.spec, "ref"); got != tt.configMap {
+				t.Errorf("IsPodSpecUsingConfigMap() = %v, want %v", got, tt.configMap)
+			}
+		})
+	}
+}
+
+func TestConsumedKeys(t *testing.T) {
+	ref := corev1.LocalObjectReference{Name: "ref"}
+	keyRef := func(key string) corev1.EnvVar {
+		return corev1.EnvVar{Name: key, ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: ref, Key: key},
+		}}
+	}
+
+	tests := []struct {
+		name string
+		spec corev1.PodSpec
+		keys []string
+		all  bool
+		used bool
+	}{
+		{
+			name: "not used",
+			spec: corev1.PodSpec{},
+		},
+		{
+			name: "secretKeyRef",
+			spec: corev1.PodSpec{Containers: []corev1.Container{{Env: []corev1.EnvVar{keyRef("username")}}}},
+			keys: []string{"username"},
+			used: true,
+		},
+		{
+			name: "keys of several containers are merged",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Env: []corev1.EnvVar{keyRef("username")}}},
+				Containers:     []corev1.Container{{Env: []corev1.EnvVar{keyRef("password"), keyRef("username")}}},
+			},
+			keys: []string{"password", "username"},
+			used: true,
+		},
+		{
+			name: "volume items",
+			spec: corev1.PodSpec{Volumes: []corev1.Volume{{Name: "vol", VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "ref", Items: []corev1.KeyToPath{{Key: "tls.crt", Path: "cert"}}},
+			}}}},
+			keys: []string{"tls.crt"},
+			used: true,
+		},
+		{
+			name: "volume without items consumes all keys",
+			spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Env: []corev1.EnvVar{keyRef("username")}}},
+				Volumes: []corev1.Volume{{Name: "vol", VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "ref"},
+				}}},
+			},
+			all:  true,
+			used: true,
+		},
+		{
+			name: "envFrom consumes all keys",
+			spec: corev1.PodSpec{Containers: []corev1.Container{{
+				EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: ref}}},
+			}}},
+			all:  true,
+			used: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, all, used := ConsumedKeys(&tt.spec, replicationv1alpha1.SourceKindSecret, "ref")
+			if !slices.Equal(keys, tt.keys) || all != tt.all || used != tt.used {
+				t.Errorf("ConsumedKeys() = %v, %v, %v, want %v, %v, %v", keys, all, used, tt.keys, tt.all, tt.used)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// Workload annotations with which consumers decide themselves whether they are
+// restarted when a Secret or ConfigMap in their namespace changes.
+const (
+	// ReloadKey is "auto" to restart on changes of every referenced Secret
+	// and ConfigMap, or "false" to never restart.
+	ReloadKey = "replizieren.dev/reload"
+	// ReloadOnKey lists the Secrets and ConfigMaps to restart on, e.g.
+	// "secret/db-creds,configmap/app-config".
+	ReloadOnKey = "replizieren.dev/reload-on"
+)
+
+// Values of the reload annotation
+const (
+	ReloadAuto     = "auto"
+	ReloadDisabled = "false"
+)
+
+// reloadMode is how a workload wants to react to changes of an object.
+type reloadMode int
+
+const (
+	// reloadDefault leaves the decision to the source.
+	reloadDefault reloadMode = iota
+	// reloadNever never restarts the workload.
+	reloadNever
+	// reloadReferenced restarts the workload if its pod spec references the object.
+	reloadReferenced
+	// reloadListed restarts the workload, whether its pod spec references the
+	// object or not.
+	reloadListed
+)
+
+// reloadModeOf returns how the workload reacts to changes of the named object.
+// The opt-out wins over reload-on, which wins over reload: "auto".
+func reloadModeOf(obj client.Object, kind replicationv1alpha1.SourceKind, name string) reloadMode {
+	annotations := obj.GetAnnotations()
+	if annotations[ReloadKey] == ReloadDisabled {
+		return reloadNever
+	}
+	for _, ref := range SplitList(annotations[ReloadOnKey]) {
+		refKind, refName, ok := strings.Cut(ref, "/")
+		if ok && refName == name && strings.EqualFold(refKind, string(kind)) {
+			return reloadListed
+		}
+	}
+	if annotations[ReloadKey] == ReloadAuto {
+		return reloadReferenced
+	}
+	return reloadDefault
+}
+
+// consumedKeysHash returns the checksum of the keys of obj a pod spec
+// consumes, or false if the pod spec does not use obj. Pod specs that only
+// consume some keys, e.g. through secretKeyRef or volume items, are not
+// restarted by changes to other keys.
+func consumedKeysHash(obj client.Object, spec *corev1.PodSpec) (string, bool) {
+	keys, all, used := ConsumedKeys(spec, SourceKindOf(obj), obj.GetName())
+	if !used {
+		return "", false
+	}
+	if all {
+		keys = nil
+	}
+	return KeysHash(obj, keys), true
+}
+
+// restartChecksum returns a function that decides whether a workload is
+// restarted for a change of obj and computes the checksum of the content it
+// consumes. Workloads that opted in through reload annotations are always
+// restarted, workloads that opted out never. Other workloads using obj are
+// restarted if their labels match rollout, which is nil if the source did
+// not enable rollout-on-update.
+func restartChecksum(obj client.Object, rollout labels.Selector) RestartWorkloadsFunc {
+	kind := SourceKindOf(obj)
+	return func(w *Workload) (string, bool) {
+		switch reloadModeOf(w.Object, kind, obj.GetName()) {
+		case reloadNever:
+			return "", false
+		case reloadListed:
+			if checksum, ok := consumedKeysHash(obj, &w.Template.Spec); ok {
+				return checksum, true
+			}
+			return KeysHash(obj, nil), true
+		case reloadReferenced:
+			return consumedKeysHash(obj, &w.Template.Spec)
+		}
+		if rollout == nil || !rollout.Matches(labels.Set(w.Object.GetLabels())) {
+			return "", false
+		}
+		return consumedKeysHash(obj, &w.Template.Spec)
+	}
+}
+
+// restartStep returns the rollout step of the namespace of obj, the source
+// or one of its replicas.
+func restartStep(obj client.Object, config ReplicationConfig) rolloutStep {
+	return rolloutStep{Namespace: obj.GetNamespace(), ChecksumOf: restartChecksum(obj, config.rolloutSelector(obj.GetNamespace()))}
+}
+
+// reloadIndexField indexes workloads by the sources they opted in to reload
+// for: "auto" for reload: "auto" and e.g. "secret/db-creds" for every entry of
+// reload-on.
+const reloadIndexField = "replizieren.dev/reload"
+
+// indexReloads returns the reload index values of a workload. Workloads that
+// opted out are not indexed.
+func indexReloads(obj client.Object) []string {
+	annotations := obj.GetAnnotations()
+	if annotations[ReloadKey] == ReloadDisabled {
+		return nil
+	}
+	var values []string
+	if annotations[ReloadKey] == ReloadAuto {
+		values = append(values, ReloadAuto)
+	}
+	for _, ref := range SplitList(annotations[ReloadOnKey]) {
+		if kind, name, ok := strings.Cut(ref, "/"); ok {
+			values = append(values, strings.ToLower(kind)+"/"+name)
+		}
+	}
+	return values
+}
+
+// ReloadIndex finds the workloads that opted in to reloads through a field
+// index of the manager cache, so sources that are neither replicated nor
+// rolled out are skipped unless a workload in their namespace opted in. If it
+// is nil, the workloads of the namespace are listed for every such source.
+type ReloadIndex struct {
+	kinds []WorkloadKind
+}
+
+// NewReloadIndex indexes the built-in workloads and those of the custom kinds
+// by their reload annotations. Custom kinds that are not installed in the
+// cluster are skipped.
+func NewReloadIndex(ctx context.Context, indexer client.FieldIndexer, kinds []WorkloadKind) (*ReloadIndex, error) {
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}, &batchv1.CronJob{}} {
+		if err := indexer.IndexField(ctx, obj, reloadIndexField, indexReloads); err != nil {
+			return nil, err
+		}
+	}
+	index := &ReloadIndex{}
+	for _, kind := range kinds {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(kind.GroupVersionKind)
+		if err := indexer.IndexField(ctx, obj, reloadIndexField, indexReloads); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, err
+		}
+		index.kinds = append(index.kinds, kind)
+	}
+	return index, nil
+}
+
+// optedIn returns true if a workload in the namespace of the source opted in
+// to reloads of it. Without an index every source may have opted-in workloads.
+func (i *ReloadIndex) optedIn(ctx context.Context, c client.Reader, source client.Object) (bool, error) {
+	if i == nil {
+		return true, nil
+	}
+	lists := []client.ObjectList{&appsv1.DeploymentList{}, &appsv1.StatefulSetList{}, &appsv1.DaemonSetList{}, &batchv1.CronJobList{}}
+	for _, kind := range i.kinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(kind.GroupVersionKind.GroupVersion().WithKind(kind.GroupVersionKind.Kind + "List"))
+		lists = append(lists, list)
+	}
+	ref := strings.ToLower(string(SourceKindOf(source))) + "/" + source.GetName()
+	for _, list := range lists {
+		for _, value := range []string{ReloadAuto, ref} {
+			if err := c.List(ctx, list, client.InNamespace(source.GetNamespace()), client.MatchingFields{reloadIndexField: value}); err != nil {
+				return false, err
+			}
+			if meta.LenList(list) > 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
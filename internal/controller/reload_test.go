@@ -0,0 +1,181 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRestartChecksum(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "apps"},
+		Data:       map[string][]byte{"password": []byte("v1")},
+	}
+	workload := func(annotations map[string]string, spec corev1.PodSpec) *Workload {
+		deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps", Annotations: annotations}}
+		return &Workload{Kind: "Deployment", Object: deploy, Template: &corev1.PodTemplateSpec{Spec: spec}}
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		spec        corev1.PodSpec
+		rollout     bool
+		want        bool
+	}{
+		{name: "no annotations, rollout disabled", spec: podSpecUsingSecret("creds")},
+		{name: "no annotations, rollout enabled", spec: podSpecUsingSecret("creds"), rollout: true, want: true},
+		{name: "rollout enabled, not referenced", spec: podSpecUsingSecret("other"), rollout: true},
+		{
+			name:        "auto, referenced",
+			annotations: map[string]string{ReloadKey: ReloadAuto},
+			spec:        podSpecUsingSecret("creds"),
+			want:        true,
+		},
+		{
+			name:        "auto, not referenced",
+			annotations: map[string]string{ReloadKey: ReloadAuto},
+			spec:        podSpecUsingSecret("other"),
+		},
+		{
+			name:        "listed, not referenced",
+			annotations: map[string]string{ReloadOnKey: "configmap/config, Secret/creds"},
+			spec:        podSpecUsingSecret("other"),
+			want:        true,
+		},
+		{
+			name:        "listed with another kind",
+			annotations: map[string]string{ReloadOnKey: "configmap/creds"},
+			spec:        podSpecUsingSecret("other"),
+		},
+		{
+			name:        "opted out",
+			annotations: map[string]string{ReloadKey: ReloadDisabled, ReloadOnKey: "secret/creds"},
+			spec:        podSpecUsingSecret("creds"),
+			rollout:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rollout labels.Selector
+			if tt.rollout {
+				rollout = labels.Everything()
+			}
+			checksum, ok := restartChecksum(secret, rollout)(workload(tt.annotations, tt.spec))
+			if ok != tt.want {
+				t.Fatalf("expected restart %v, got %v", tt.want, ok)
+			}
+			if ok && checksum != KeysHash(secret, nil) {
+				t.Errorf("expected checksum of the secret, got %q", checksum)
+			}
+		})
+	}
+}
+
+func TestSyncSource_ReloadAnnotations(t *testing.T) {
+	// A local Secret without replication or rollout annotations
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "apps"},
+		Data:       map[string][]byte{"password": []byte("v1")},
+	}
+	deployment := func(name string, annotations map[string]string, spec corev1.PodSpec) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps", Annotations: annotations},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: spec}},
+		}
+	}
+	f := newSyncFixture(t, source,
+		deployment("auto", map[string]string{ReloadKey: ReloadAuto}, podSpecUsingSecret("creds")),
+		deployment("listed", map[string]string{ReloadOnKey: "secret/creds"}, corev1.PodSpec{}),
+		deployment("plain", nil, podSpecUsingSecret("creds")),
+	)
+
+	f.sync(source)
+	if f.checksum(source, "apps", "auto") == "" {
+		t.Error("expected Deployment with reload: auto to be restarted")
+	}
+	if f.checksum(source, "apps", "listed") == "" {
+		t.Error("expected Deployment listing the secret in reload-on to be restarted")
+	}
+	if f.checksum(source, "apps", "plain") != "" {
+		t.Error("expected Deployment without reload annotations to be left alone")
+	}
+}
+
+func TestReloadIndex_OptedIn(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	builder := fake.NewClientBuilder().WithScheme(testScheme)
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}, &batchv1.CronJob{}} {
+		builder = builder.WithIndex(obj, reloadIndexField, indexReloads)
+	}
+	objectMeta := func(namespace, name string, annotations map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations}
+	}
+	c := builder.WithObjects(
+		&appsv1.Deployment{ObjectMeta: objectMeta("apps", "opted-out", map[string]string{ReloadKey: ReloadDisabled, ReloadOnKey: "secret/creds"})},
+		&appsv1.Deployment{ObjectMeta: objectMeta("apps", "config", map[string]string{ReloadOnKey: "configmap/creds"})},
+		&batchv1.CronJob{ObjectMeta: objectMeta("other", "listed", map[string]string{ReloadOnKey: "secret/creds"})},
+		&appsv1.DaemonSet{ObjectMeta: objectMeta("other", "auto", map[string]string{ReloadKey: ReloadAuto})},
+	).Build()
+	ctx := context.Background()
+	index := &ReloadIndex{}
+	optedIn := func(source client.Object) bool {
+		t.Helper()
+		ok, err := index.optedIn(ctx, c, source)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return ok
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "apps"}}
+
+	if optedIn(secret) {
+		t.Error("expected opted-out and other workloads to not opt in to the secret")
+	}
+	if !optedIn(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "apps"}}) {
+		t.Error("expected the workload listing the ConfigMap to opt in")
+	}
+	if !optedIn(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "any", Namespace: "other"}}) {
+		t.Error("expected the workload with reload: auto to opt in")
+	}
+
+	// Kinds in reload-on are not case sensitive
+	if err := c.Create(ctx, &appsv1.StatefulSet{ObjectMeta: objectMeta("apps", "listed", map[string]string{ReloadOnKey: "Secret/creds"})}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !optedIn(secret) {
+		t.Error("expected the StatefulSet listing the secret to opt in")
+	}
+
+	// Without an index every source is checked
+	index = nil
+	if !optedIn(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "empty"}}) {
+		t.Error("expected sources to opt in without an index")
+	}
+}
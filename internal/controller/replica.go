@@ -0,0 +1,340 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// Replica bookkeeping keys
+const (
+	// ReplicaLabel marks objects that were created by the operator.
+	ReplicaLabel = "replizieren.dev/replica"
+	// SourceAnnotation records the "namespace/name" of the source of a replica.
+	SourceAnnotation = "replizieren.dev/source"
+	// HashAnnotation records the content hash of a replica when it was written,
+	// so that changes made by others can be detected.
+	HashAnnotation = "replizieren.dev/hash"
+)
+
+// annotationPrefix is the prefix shared by all replizieren annotations.
+const annotationPrefix = "replizieren.dev/"
+
+// ReplicaOutcome describes what ReplicateObject did in a target namespace.
+type ReplicaOutcome string
+
+const (
+	// ReplicaCreated means the replica did not exist and was created.
+	ReplicaCreated ReplicaOutcome = "Created"
+	// ReplicaUpdated means an existing replica was updated.
+	ReplicaUpdated ReplicaOutcome = "Updated"
+	// ReplicaUnchanged means the replica was already up to date.
+	ReplicaUnchanged ReplicaOutcome = "Unchanged"
+	// ReplicaRestored means the content of the replica was changed by someone
+	// else and has been restored.
+	ReplicaRestored ReplicaOutcome = "Restored"
+	// ReplicaConflict means an unmanaged object with the same name exists and
+	// the conflict policy did not allow overwriting it.
+	ReplicaConflict ReplicaOutcome = "Conflict"
+	// ReplicaPending means the replica was left untouched, because the target
+	// belongs to a wave that has not received the change yet.
+	ReplicaPending ReplicaOutcome = "Pending"
+)
+
+// Written returns true if the outcome changed the replica.
+func (o ReplicaOutcome) Written() bool {
+	return o == ReplicaCreated || o == ReplicaUpdated || o == ReplicaRestored
+}
+
+// IsReplica returns true if the object was created by the operator.
+func IsReplica(obj client.Object) bool {
+	return obj.GetLabels()[ReplicaLabel] == "true"
+}
+
+// SourceOf returns the source of a replica.
+func SourceOf(obj client.Object) (types.NamespacedName, bool) {
+	ref := obj.GetAnnotations()[SourceAnnotation]
+	namespace, name, found := strings.Cut(ref, "/")
+	if !found || namespace == "" || name == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
+
+// isManagedReplicaOf returns true if existing is a replica of source.
+func isManagedReplicaOf(existing, source client.Object) bool {
+	ref, ok := SourceOf(existing)
+	return ok && IsReplica(existing) && ref == client.ObjectKeyFromObject(source)
+}
+
+// SourceKindOf returns the replication kind of a source object.
+func SourceKindOf(obj client.Object) replicationv1alpha1.SourceKind {
+	switch obj.(type) {
+	case *corev1.Secret:
+		return replicationv1alpha1.SourceKindSecret
+	case *corev1.ConfigMap:
+		return replicationv1alpha1.SourceKindConfigMap
+	}
+	return ""
+}
+
+// newSourceObject returns an empty object of the given replication kind.
+func newSourceObject(kind replicationv1alpha1.SourceKind) client.Object {
+	switch kind {
+	case replicationv1alpha1.SourceKindSecret:
+		return &corev1.Secret{}
+	case replicationv1alpha1.SourceKindConfigMap:
+		return &corev1.ConfigMap{}
+	}
+	return nil
+}
+
+// IsOrphaned returns true if the source of the replica was deleted or no
+// longer replicates to the namespace of the replica. The operator leaves such
+// replicas in place, so they can be changed and deleted like other objects.
+func IsOrphaned(ctx context.Context, c client.Client, replica client.Object) (bool, error) {
+	ref, ok := SourceOf(replica)
+	source := newSourceObject(SourceKindOf(replica))
+	if !ok || source == nil {
+		return false, nil
+	}
+	if err := c.Get(ctx, ref, source); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	config, err := ResolveReplicationConfig(ctx, c, source)
+	if err != nil {
+		return false, err
+	}
+	if config.SkipReplication {
+		return true, nil
+	}
+	var namespace corev1.Namespace
+	if err := c.Get(ctx, client.ObjectKey{Name: replica.GetNamespace()}, &namespace); err != nil {
+		return false, err
+	}
+	return !config.MatchesNamespace(&namespace, source.GetNamespace()), nil
+}
+
+// newReplica builds the desired replica of source in the target namespace.
+// Replication annotations are stripped so that replicas never act as sources.
+func newReplica(source client.Object, namespace string, config ReplicationConfig) client.Object {
+	replica, ok := source.DeepCopyObject().(client.Object)
+	if !ok {
+		return nil
+	}
+	replica.SetNamespace(namespace)
+	replica.SetResourceVersion("")
+	replica.SetUID("")
+	replica.SetGeneration(0)
+	replica.SetCreationTimestamp(metav1.Time{})
+	replica.SetManagedFields(nil)
+	replica.SetOwnerReferences(nil)
+	replica.SetFinalizers(nil)
+
+	labels := map[string]string{}
+	for k, v := range source.GetLabels() {
+		labels[k] = v
+	}
+	labels[ReplicaLabel] = "true"
+	replica.SetLabels(labels)
+
+	annotations := map[string]string{}
+	for k, v := range source.GetAnnotations() {
+		if !strings.HasPrefix(k, annotationPrefix) {
+			annotations[k] = v
+		}
+	}
+	annotations[SourceAnnotation] = source.GetNamespace() + "/" + source.GetName()
+	replica.SetAnnotations(annotations)
+
+	if len(config.Keys) > 0 {
+		filterKeys(replica, config.Keys)
+	}
+	annotations[HashAnnotation] = PayloadHash(replica)
+	return replica
+}
+
+// replicaOutdated returns true if ReplicateObject would overwrite an existing
+// object in the namespace, a replica with different content or an unmanaged
+// object.
+func replicaOutdated(ctx context.Context, c client.Client, source client.Object, namespace string, config ReplicationConfig) (bool, error) {
+	existing := newSourceObject(SourceKindOf(source))
+	err := c.Get(ctx, types.NamespacedName{Name: source.GetName(), Namespace: namespace}, existing)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !isManagedReplicaOf(existing, source) {
+		return config.ConflictPolicy != replicationv1alpha1.ConflictPolicySkip, nil
+	}
+	return !isUpToDate(existing, newReplica(source, namespace, config)), nil
+}
+
+// HasDrifted returns true if the content of a replica no longer matches the
+// hash recorded when it was written.
+func HasDrifted(replica client.Object) bool {
+	hash, ok := replica.GetAnnotations()[HashAnnotation]
+	return ok && hash != PayloadHash(replica)
+}
+
+// isUpToDate returns true if existing already matches the desired replica.
+func isUpToDate(existing, desired client.Object) bool {
+	if PayloadHash(existing) != PayloadHash(desired) ||
+		!equality.Semantic.DeepEqual(existing.GetLabels(), desired.GetLabels()) ||
+		!equality.Semantic.DeepEqual(existing.GetAnnotations(), desired.GetAnnotations()) {
+		return false
+	}
+	if e, ok := existing.(*corev1.Secret); ok {
+		return e.Type == desired.(*corev1.Secret).Type
+	}
+	return true
+}
+
+// filterKeys removes all data keys that are not listed in keys.
+func filterKeys(obj client.Object, keys []string) {
+	allowed := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		allowed[k] = true
+	}
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		for k := range o.Data {
+			if !allowed[k] {
+				delete(o.Data, k)
+			}
+		}
+		for k := range o.StringData {
+			if !allowed[k] {
+				delete(o.StringData, k)
+			}
+		}
+	case *corev1.ConfigMap:
+		for k := range o.Data {
+			if !allowed[k] {
+				delete(o.Data, k)
+			}
+		}
+		for k := range o.BinaryData {
+			if !allowed[k] {
+				delete(o.BinaryData, k)
+			}
+		}
+	}
+}
+
+// PayloadHash returns a hash over the data keys and values of a Secret or ConfigMap.
+func PayloadHash(obj client.Object) string {
+	return KeysHash(obj, nil)
+}
+
+// KeysHash returns a hash over the given data keys and their values of a
+// Secret or ConfigMap. All keys are hashed if keys is nil.
+func KeysHash(obj client.Object, keys []string) string {
+	entries := map[string][]byte{}
+	add := func(field, k string, v []byte) {
+		if keys == nil || slices.Contains(keys, k) {
+			entries[field+"/"+k] = v
+		}
+	}
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		for k, v := range o.Data {
+			add("data", k, v)
+		}
+		for k, v := range o.StringData {
+			add("stringData", k, []byte(v))
+		}
+	case *corev1.ConfigMap:
+		for k, v := range o.Data {
+			add("data", k, []byte(v))
+		}
+		for k, v := range o.BinaryData {
+			add("binaryData", k, v)
+		}
+	}
+
+	names := make([]string, 0, len(entries))
+	for k := range entries {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	h := sha256.New()
+	for _, k := range names {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(entries[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReplicateObject creates or updates the replica of source in the target namespace.
+func ReplicateObject(
+	ctx context.Context,
+	c client.Client,
+	source client.Object,
+	namespace string,
+	config ReplicationConfig,
+) (ReplicaOutcome, error) {
+	replica := newReplica(source, namespace, config)
+	if replica == nil {
+		return "", fmt.Errorf("unsupported source type %T", source)
+	}
+
+	existing := newSourceObject(SourceKindOf(source))
+	err := c.Get(ctx, types.NamespacedName{Name: replica.GetName(), Namespace: namespace}, existing)
+	if err != nil && errors.IsNotFound(err) {
+		return ReplicaCreated, c.Create(ctx, replica)
+	} else if err != nil {
+		return "", err
+	}
+
+	managed := isManagedReplicaOf(existing, source)
+	if !managed && config.ConflictPolicy == replicationv1alpha1.ConflictPolicySkip {
+		return ReplicaConflict, nil
+	}
+	if managed && isUpToDate(existing, replica) {
+		return ReplicaUnchanged, nil
+	}
+
+	outcome := ReplicaUpdated
+	if managed && HasDrifted(existing) {
+		outcome = ReplicaRestored
+	}
+	replica.SetResourceVersion(existing.GetResourceVersion())
+	return outcome, c.Update(ctx, replica)
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestNewReplica_StripsReplicationAnnotations(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "creds",
+			Namespace:       "source-ns",
+			ResourceVersion: "42",
+			UID:             "uid",
+			Labels:          map[string]string{"app": "db"},
+			Annotations: map[string]string{
+				ReplicateAllKey: "true",
+				"team":          "platform",
+			},
+		},
+	}
+	replica := newReplica(source, "target-ns", ReplicationConfig{})
+
+	if replica.GetNamespace() != "target-ns" {
+		t.Errorf("expected namespace target-ns, got %s", replica.GetNamespace())
+	}
+	if replica.GetResourceVersion() != "" || replica.GetUID() != "" {
+		t.Error("expected resource version and UID to be cleared")
+	}
+	if _, ok := replica.GetAnnotations()[ReplicateAllKey]; ok {
+		t.Error("expected replication annotations to be stripped")
+	}
+	if replica.GetAnnotations()["team"] != "platform" {
+		t.Error("expected other annotations to be kept")
+	}
+	if !IsReplica(replica) {
+		t.Error("expected replica label to be set")
+	}
+	ref, ok := SourceOf(replica)
+	if !ok || ref != (types.NamespacedName{Namespace: "source-ns", Name: "creds"}) {
+		t.Errorf("expected source annotation to point at source-ns/creds, got %v", ref)
+	}
+	if IsReplica(source) {
+		t.Error("expected source to be left untouched")
+	}
+}
+
+func TestNewReplica_FiltersKeys(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "source-ns"},
+		Data:       map[string]string{"a": "1", "b": "2"},
+		BinaryData: map[string][]byte{"c": []byte("3")},
+	}
+	replica := newReplica(source, "target-ns", ReplicationConfig{Keys: []string{"a", "c"}}).(*corev1.ConfigMap)
+
+	if _, ok := replica.Data["b"]; ok {
+		t.Error("expected key b to be filtered")
+	}
+	if replica.Data["a"] != "1" || string(replica.BinaryData["c"]) != "3" {
+		t.Errorf("expected keys a and c to be kept, got %v %v", replica.Data, replica.BinaryData)
+	}
+	if len(source.Data) != 2 {
+		t.Error("expected source data to be left untouched")
+	}
+}
+
+func TestSourceOf_Invalid(t *testing.T) {
+	for _, value := range []string{"", "name-only", "/name", "ns/"} {
+		obj := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{SourceAnnotation: value}}}
+		if _, ok := SourceOf(obj); ok {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestIsOrphaned(t *testing.T) {
+	source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        "creds",
+		Namespace:   "source-ns",
+		Annotations: map[string]string{ReplicateKey: "team-a"},
+	}}
+	replica := func(namespace string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:        "creds",
+			Namespace:   namespace,
+			Labels:      map[string]string{ReplicaLabel: "true"},
+			Annotations: map[string]string{SourceAnnotation: "source-ns/creds"},
+		}}
+	}
+	f := newSyncFixture(t, source, replica("team-a"), replica("team-b"))
+	orphaned := func(replica *corev1.Secret) bool {
+		t.Helper()
+		orphaned, err := IsOrphaned(f.ctx, f.client, replica)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return orphaned
+	}
+
+	if orphaned(replica("team-a")) {
+		t.Error("expected the replica in a target namespace to not be orphaned")
+	}
+	if !orphaned(replica("team-b")) {
+		t.Error("expected the replica in a namespace that is no longer targeted to be orphaned")
+	}
+
+	delete(source.Annotations, ReplicateKey)
+	f.update(source)
+	if !orphaned(replica("team-a")) {
+		t.Error("expected the replica of a source that stopped replicating to be orphaned")
+	}
+
+	if err := f.client.Delete(f.ctx, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !orphaned(replica("team-a")) {
+		t.Error("expected the replica of a deleted source to be orphaned")
+	}
+}
+
+func TestReplicateObject_ConflictPolicy(t *testing.T) {
+	unmanaged := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "target-ns"},
+		Data:       map[string][]byte{"key": []byte("local")},
+	}
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Data:       map[string][]byte{"key": []byte("shared")},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(unmanaged).Build()
+	config := ReplicationConfig{ConflictPolicy: replicationv1alpha1.ConflictPolicySkip}
+	outcome, err := ReplicateObject(context.Background(), c, source, "target-ns", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome != ReplicaConflict {
+		t.Errorf("expected conflict outcome, got %s", outcome)
+	}
+
+	config.ConflictPolicy = replicationv1alpha1.ConflictPolicyOverwrite
+	outcome, err = ReplicateObject(context.Background(), c, source, "target-ns", config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if outcome != ReplicaUpdated {
+		t.Errorf("expected updated outcome, got %s", outcome)
+	}
+
+	var replica corev1.Secret
+	if err := c.Get(context.Background(), types.NamespacedName{Name: "creds", Namespace: "target-ns"}, &replica); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(replica.Data["key"]) != "shared" || !IsReplica(&replica) {
+		t.Error("expected existing object to be overwritten and marked as replica")
+	}
+}
+
+func TestReplicateObject_UnchangedAndRestored(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Data:       map[string][]byte{"key": []byte("shared")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	ctx := context.Background()
+
+	outcome, err := ReplicateObject(ctx, c, source, "target-ns", ReplicationConfig{})
+	if err != nil || outcome != ReplicaCreated {
+		t.Fatalf("expected created outcome, got %s, %v", outcome, err)
+	}
+
+	outcome, err = ReplicateObject(ctx, c, source, "target-ns", ReplicationConfig{})
+	if err != nil || outcome != ReplicaUnchanged {
+		t.Fatalf("expected unchanged outcome, got %s, %v", outcome, err)
+	}
+
+	var replica corev1.Secret
+	key := types.NamespacedName{Name: "creds", Namespace: "target-ns"}
+	if err := c.Get(ctx, key, &replica); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if HasDrifted(&replica) {
+		t.Error("expected freshly written replica to not have drifted")
+	}
+	replica.Data["key"] = []byte("edited")
+	if err := c.Update(ctx, &replica); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !HasDrifted(&replica) {
+		t.Error("expected edited replica to have drifted")
+	}
+
+	outcome, err = ReplicateObject(ctx, c, source, "target-ns", ReplicationConfig{})
+	if err != nil || outcome != ReplicaRestored {
+		t.Fatalf("expected restored outcome, got %s, %v", outcome, err)
+	}
+
+	source.Data["key"] = []byte("rotated")
+	outcome, err = ReplicateObject(ctx, c, source, "target-ns", ReplicationConfig{})
+	if err != nil || outcome != ReplicaUpdated {
+		t.Fatalf("expected updated outcome after source change, got %s, %v", outcome, err)
+	}
+}
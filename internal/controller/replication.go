@@ -0,0 +1,386 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// Condition reasons reported on Replications.
+const (
+	reasonSynced         = "Synced"
+	reasonTargetsFailed  = "TargetsNotSynced"
+	reasonSourceNotFound = "SourceNotFound"
+	reasonPausedBySource = "PausedBySource"
+	reasonPausedGlobally = "PausedGlobally"
+)
+
+// targetResult is the outcome of replicating a source into one namespace.
+type targetResult struct {
+	Namespace string
+	Outcome   ReplicaOutcome
+	Hash      string
+	Err       error
+	// Job is the Job run after the replica was updated, if any.
+	Job *replicationv1alpha1.JobStatus
+	// WindowOpens is when the maintenance window opens that holds the update
+	// of the replica or the restart of its workloads.
+	WindowOpens time.Time
+}
+
+// targetErrors aggregates the errors of all failed targets, so the source is
+// requeued with backoff until every target succeeded.
+func targetErrors(results map[string]targetResult) error {
+	namespaces := slices.Sorted(maps.Keys(results))
+	var errs []error
+	for _, ns := range namespaces {
+		if err := results[ns].Err; err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", ns, err))
+		}
+	}
+	return kerrors.NewAggregate(errs)
+}
+
+// ReplicationsForSource returns the Replications that reference the source.
+func ReplicationsForSource(ctx context.Context, c client.Client, obj client.Object) ([]replicationv1alpha1.Replication, error) {
+	return replicationsReferencing(ctx, c, SourceKindOf(obj), client.ObjectKeyFromObject(obj))
+}
+
+// replicationsReferencing returns the Replications in the namespace of key that
+// reference a source of the given kind and name.
+func replicationsReferencing(
+	ctx context.Context,
+	c client.Client,
+	kind replicationv1alpha1.SourceKind,
+	key types.NamespacedName,
+) ([]replicationv1alpha1.Replication, error) {
+	var list replicationv1alpha1.ReplicationList
+	if err := c.List(ctx, &list, client.InNamespace(key.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var matching []replicationv1alpha1.Replication
+	for _, replication := range list.Items {
+		if replication.Spec.Source.Kind == kind && replication.Spec.Source.Name == key.Name {
+			matching = append(matching, replication)
+		}
+	}
+	return matching, nil
+}
+
+// requestForReplication returns the reconcile request for the source of a
+// Replication if it is of the given kind.
+func requestForReplication(obj client.Object, kind replicationv1alpha1.SourceKind) []reconcile.Request {
+	replication, ok := obj.(*replicationv1alpha1.Replication)
+	if !ok || replication.Spec.Source.Kind != kind {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: replication.Namespace, Name: replication.Spec.Source.Name},
+	}}
+}
+
+// ownTargets returns the namespaces a Replication selects on its own.
+func ownTargets(ctx context.Context, c client.Client, replication *replicationv1alpha1.Replication) ([]string, error) {
+	config := mergeRules(nil, replication.Namespace, replicationRules([]replicationv1alpha1.Replication{*replication}))
+	if config.SkipReplication {
+		return nil, nil
+	}
+	return ResolveTargetNamespaces(ctx, c, config, replication.Namespace)
+}
+
+// buildTargetStatuses converts the results of the namespaces a Replication
+// selects into target statuses. LastSyncTime is only bumped when the state or
+// content of a target changed, Failures counts consecutive failed attempts.
+func buildTargetStatuses(
+	previous []replicationv1alpha1.TargetStatus,
+	targets []string,
+	results map[string]targetResult,
+	now metav1.Time,
+) []replicationv1alpha1.TargetStatus {
+	sorted := slices.Clone(targets)
+	slices.Sort(sorted)
+
+	var statuses []replicationv1alpha1.TargetStatus
+	for _, ns := range sorted {
+		result, ok := results[ns]
+		if !ok {
+			continue
+		}
+
+		status := replicationv1alpha1.TargetStatus{Namespace: ns}
+		switch {
+		case result.Err != nil:
+			status.State = replicationv1alpha1.TargetStateFailed
+			status.LastError = result.Err.Error()
+		case result.Outcome == ReplicaPending:
+			status.State = replicationv1alpha1.TargetStatePending
+		case result.Outcome == ReplicaConflict:
+			status.State = replicationv1alpha1.TargetStateConflict
+			status.LastError = "target contains an object not managed by replizieren"
+		default:
+			status.State = replicationv1alpha1.TargetStateSynced
+			status.LastSyncedHash = result.Hash
+			status.Job = result.Job
+		}
+
+		if !result.WindowOpens.IsZero() {
+			status.WindowOpens = &metav1.Time{Time: result.WindowOpens}
+		}
+
+		i := slices.IndexFunc(previous, func(t replicationv1alpha1.TargetStatus) bool { return t.Namespace == ns })
+		if result.Err != nil {
+			status.Failures = 1
+		}
+		if i >= 0 && result.Err != nil {
+			// Keep the hash of the last successful sync around
+			status.LastSyncedHash = previous[i].LastSyncedHash
+			status.Failures = previous[i].Failures + 1
+		}
+		if i >= 0 && result.Outcome == ReplicaPending {
+			// The replica still has the content of the last successful sync
+			status.LastSyncedHash = previous[i].LastSyncedHash
+			status.Job = previous[i].Job
+		}
+		if i >= 0 && previous[i].State == status.State && previous[i].LastSyncedHash == status.LastSyncedHash {
+			status.LastSyncTime = previous[i].LastSyncTime
+		} else {
+			status.LastSyncTime = &now
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// setReadyConditions sets the Ready and Degraded conditions from the target statuses.
+func setReadyConditions(status *replicationv1alpha1.ReplicationStatus, generation int64) {
+	var notSynced int
+	for _, target := range status.Targets {
+		if target.State != replicationv1alpha1.TargetStateSynced {
+			notSynced++
+		}
+	}
+
+	if notSynced == 0 {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               replicationv1alpha1.ConditionReady,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonSynced,
+			Message:            fmt.Sprintf("Replicated to %d namespaces", len(status.Targets)),
+			ObservedGeneration: generation,
+		})
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               replicationv1alpha1.ConditionDegraded,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonSynced,
+			Message:            "All targets are in sync",
+			ObservedGeneration: generation,
+		})
+		return
+	}
+
+	message := fmt.Sprintf("%d of %d targets are not in sync", notSynced, len(status.Targets))
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               replicationv1alpha1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonTargetsFailed,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               replicationv1alpha1.ConditionDegraded,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonTargetsFailed,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// setWaveStatus reports the progress of the waves and sets the Progressing
+// condition. Both are removed if the source is not propagated in waves.
+func setWaveStatus(status *replicationv1alpha1.ReplicationStatus, waves []replicationv1alpha1.WaveStatus, generation int64) {
+	status.Waves = waves
+	if len(waves) == 0 {
+		meta.RemoveStatusCondition(&status.Conditions, replicationv1alpha1.ConditionProgressing)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               replicationv1alpha1.ConditionProgressing,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonWavesCompleted,
+		Message:            fmt.Sprintf("All %d waves are healthy", len(waves)),
+		ObservedGeneration: generation,
+	}
+	for _, wave := range waves {
+		if wave.State == replicationv1alpha1.WaveStateHalted {
+			condition.Reason = reasonWaveHalted
+			condition.Message = fmt.Sprintf("Wave %s failed: %s", wave.Name, wave.Message)
+			break
+		}
+		if wave.State != replicationv1alpha1.WaveStateHealthy {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = reasonWavesProgressing
+			condition.Message = fmt.Sprintf("Wave %s is %s", wave.Name, strings.ToLower(string(wave.State)))
+			if wave.Message != "" {
+				condition.Message += ": " + wave.Message
+			}
+			break
+		}
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// setRolloutCondition reports the workloads whose rollout failed after they
+// were restarted for the current content. The condition is removed when no
+// rollout failed.
+func setRolloutCondition(status *replicationv1alpha1.ReplicationStatus, failed []string, generation int64) {
+	if len(failed) == 0 {
+		meta.RemoveStatusCondition(&status.Conditions, replicationv1alpha1.ConditionRolloutFailed)
+		return
+	}
+	message := failed[0] + " exceeded its progress deadline"
+	if len(failed) > 1 {
+		message = fmt.Sprintf("%s and %d more workloads exceeded their progress deadline", failed[0], len(failed)-1)
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               replicationv1alpha1.ConditionRolloutFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonProgressDeadlineExceeded,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+}
+
+// updateReplicationStatus writes the status of a Replication from the results
+// of the last sync. The status is only written when it changed.
+func updateReplicationStatus(
+	ctx context.Context,
+	c client.Client,
+	replication *replicationv1alpha1.Replication,
+	results map[string]targetResult,
+	waves []replicationv1alpha1.WaveStatus,
+	failedRollouts []string,
+) error {
+	targets, err := ownTargets(ctx, c, replication)
+	if err != nil {
+		return err
+	}
+
+	status := replication.Status.DeepCopy()
+	status.ObservedGeneration = replication.Generation
+	status.Targets = buildTargetStatuses(replication.Status.Targets, targets, results, metav1.NewTime(time.Now()))
+	setReadyConditions(status, replication.Generation)
+	setWaveStatus(status, waves, replication.Generation)
+	setRolloutCondition(status, failedRollouts, replication.Generation)
+	meta.RemoveStatusCondition(&status.Conditions, replicationv1alpha1.ConditionPaused)
+
+	if equality.Semantic.DeepEqual(&replication.Status, status) {
+		return nil
+	}
+	replication.Status = *status
+	return c.Status().Update(ctx, replication)
+}
+
+// markSourceNotFound reports a missing source on all Replications referencing it.
+func markSourceNotFound(
+	ctx context.Context,
+	c client.Client,
+	kind replicationv1alpha1.SourceKind,
+	key types.NamespacedName,
+) error {
+	replications, err := replicationsReferencing(ctx, c, kind, key)
+	if err != nil {
+		return err
+	}
+
+	for i := range replications {
+		replication := &replications[i]
+		status := replication.Status.DeepCopy()
+		status.ObservedGeneration = replication.Generation
+		message := fmt.Sprintf("%s %s not found", kind, key.Name)
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               replicationv1alpha1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             reasonSourceNotFound,
+			Message:            message,
+			ObservedGeneration: replication.Generation,
+		})
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               replicationv1alpha1.ConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			Reason:             reasonSourceNotFound,
+			Message:            message,
+			ObservedGeneration: replication.Generation,
+		})
+
+		if equality.Semantic.DeepEqual(&replication.Status, status) {
+			continue
+		}
+		replication.Status = *status
+		if err := c.Status().Update(ctx, replication); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markPaused reports paused replication on the Replications of a source. The
+// targets and the Ready condition keep the state of the last sync.
+func markPaused(
+	ctx context.Context,
+	c client.Client,
+	replications []replicationv1alpha1.Replication,
+	reason string,
+	message string,
+) error {
+	for i := range replications {
+		replication := &replications[i]
+		status := replication.Status.DeepCopy()
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               replicationv1alpha1.ConditionPaused,
+			Status:             metav1.ConditionTrue,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: replication.Generation,
+		})
+
+		if equality.Semantic.DeepEqual(&replication.Status, status) {
+			continue
+		}
+		replication.Status = *status
+		if err := c.Status().Update(ctx, replication); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestPayloadHash(t *testing.T) {
+	a := &corev1.ConfigMap{Data: map[string]string{"a": "1", "b": "2"}}
+	b := &corev1.ConfigMap{Data: map[string]string{"b": "2", "a": "1"}}
+	if PayloadHash(a) != PayloadHash(b) {
+		t.Error("expected hash to be independent of map order")
+	}
+
+	b.Data["a"] = "changed"
+	if PayloadHash(a) == PayloadHash(b) {
+		t.Error("expected hash to change with content")
+	}
+
+	binary := &corev1.ConfigMap{BinaryData: map[string][]byte{"a": []byte("1"), "b": []byte("2")}}
+	if PayloadHash(a) == PayloadHash(binary) {
+		t.Error("expected data and binaryData to hash differently")
+	}
+}
+
+func TestMergeRules_ReplicationOptionsWinOverPolicies(t *testing.T) {
+	replications := []replicationv1alpha1.Replication{{
+		ObjectMeta: metav1.ObjectMeta{Name: "r", Namespace: "source-ns"},
+		Spec: replicationv1alpha1.ReplicationSpec{
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"ns1"}},
+			Options: replicationv1alpha1.ReplicationOptions{Keys: []string{"a"}},
+		},
+	}}
+	policies := []replicationv1alpha1.ReplicationPolicy{
+		newPolicy("p", replicationv1alpha1.ReplicationPolicySpec{
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"ns2"}},
+			Options: replicationv1alpha1.ReplicationOptions{Keys: []string{"b"}},
+		}),
+	}
+
+	rules := append(replicationRules(replications), policyRules(policies)...)
+	config := mergeRules(nil, "source-ns", rules)
+	if len(config.TargetNamespaces) != 2 {
+		t.Errorf("expected targets of replication and policy, got %v", config.TargetNamespaces)
+	}
+	if len(config.Keys) != 1 || config.Keys[0] != "a" {
+		t.Errorf("expected replication keys to win, got %v", config.Keys)
+	}
+}
+
+func TestReplicationRules_TenantDefaults(t *testing.T) {
+	replications := []replicationv1alpha1.Replication{{
+		ObjectMeta: metav1.ObjectMeta{Name: "r", Namespace: "source-ns"},
+		Spec: replicationv1alpha1.ReplicationSpec{
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"kube-system", "ns1"}},
+		},
+	}}
+
+	config := mergeRules(nil, "source-ns", replicationRules(replications))
+	if len(config.TargetNamespaces) != 1 || config.TargetNamespaces[0] != "ns1" {
+		t.Errorf("expected system namespaces to be dropped, got %v", config.TargetNamespaces)
+	}
+	if config.ConflictPolicy != replicationv1alpha1.ConflictPolicySkip {
+		t.Errorf("expected Replications to default to Skip, got %q", config.ConflictPolicy)
+	}
+
+	// Settings the webhook rejects are ignored
+	replications[0].Spec.Options.ConflictPolicy = replicationv1alpha1.ConflictPolicyOverwrite
+	replications[0].Spec.Targets.AllNamespaces = true
+	replications[0].Spec.Targets.NamespaceSelector = &metav1.LabelSelector{}
+	config = mergeRules(nil, "source-ns", replicationRules(replications))
+	if config.ConflictPolicy != replicationv1alpha1.ConflictPolicySkip {
+		t.Errorf("expected Replications to never overwrite, got %q", config.ConflictPolicy)
+	}
+	if config.ReplicateAll || len(config.NamespaceSelectors) > 0 {
+		t.Errorf("expected Replications to only name namespaces, got %+v", config)
+	}
+	if replications[0].Spec.Targets.Namespaces[0] != "kube-system" {
+		t.Error("expected the Replication itself to be left unchanged")
+	}
+}
+
+func TestBuildTargetStatuses(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour))
+	now := metav1.NewTime(time.Now())
+	previous := []replicationv1alpha1.TargetStatus{
+		{Namespace: "unchanged", State: replicationv1alpha1.TargetStateSynced, LastSyncedHash: "h1", LastSyncTime: &earlier},
+		{Namespace: "failing", State: replicationv1alpha1.TargetStateSynced, LastSyncedHash: "h0", LastSyncTime: &earlier},
+	}
+	results := map[string]targetResult{
+		"unchanged": {Namespace: "unchanged", Outcome: ReplicaUpdated, Hash: "h1"},
+		"failing":   {Namespace: "failing", Err: errors.New("quota exceeded")},
+		"conflict":  {Namespace: "conflict", Outcome: ReplicaConflict, Hash: "h1"},
+	}
+
+	statuses := buildTargetStatuses(previous, []string{"unchanged", "failing", "conflict", "excluded"}, results, now)
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 target statuses, got %d", len(statuses))
+	}
+	byNamespace := map[string]replicationv1alpha1.TargetStatus{}
+	for _, s := range statuses {
+		byNamespace[s.Namespace] = s
+	}
+
+	if s := byNamespace["unchanged"]; !s.LastSyncTime.Equal(&earlier) {
+		t.Error("expected sync time of unchanged target to be kept")
+	}
+	if s := byNamespace["failing"]; s.State != replicationv1alpha1.TargetStateFailed ||
+		s.LastError != "quota exceeded" || s.LastSyncedHash != "h0" {
+		t.Errorf("expected failed target to keep last synced hash and report error, got %+v", s)
+	}
+	if s := byNamespace["conflict"]; s.State != replicationv1alpha1.TargetStateConflict || !s.LastSyncTime.Equal(&now) {
+		t.Errorf("expected conflict target with new sync time, got %+v", s)
+	}
+}
+
+func TestBuildTargetStatuses_CountsFailures(t *testing.T) {
+	now := metav1.NewTime(time.Now())
+	failed := map[string]targetResult{"ns": {Namespace: "ns", Err: errors.New("webhook timeout")}}
+
+	statuses := buildTargetStatuses(nil, []string{"ns"}, failed, now)
+	if statuses[0].Failures != 1 {
+		t.Errorf("expected first failure to be counted, got %d", statuses[0].Failures)
+	}
+	statuses = buildTargetStatuses(statuses, []string{"ns"}, failed, now)
+	if statuses[0].Failures != 2 {
+		t.Errorf("expected consecutive failures to be counted, got %d", statuses[0].Failures)
+	}
+
+	synced := map[string]targetResult{"ns": {Namespace: "ns", Outcome: ReplicaCreated, Hash: "h1"}}
+	statuses = buildTargetStatuses(statuses, []string{"ns"}, synced, now)
+	if statuses[0].Failures != 0 || statuses[0].LastError != "" {
+		t.Errorf("expected failures to be reset after a successful sync, got %+v", statuses[0])
+	}
+}
+
+func TestTargetErrors(t *testing.T) {
+	if err := targetErrors(map[string]targetResult{"ns1": {Outcome: ReplicaCreated}}); err != nil {
+		t.Errorf("expected no error without failed targets, got %v", err)
+	}
+
+	err := targetErrors(map[string]targetResult{
+		"ns1": {Outcome: ReplicaCreated},
+		"ns2": {Err: errors.New("quota exceeded")},
+		"ns3": {Err: errors.New("conflict")},
+	})
+	if err == nil {
+		t.Fatal("expected failed targets to be returned as error")
+	}
+	if expected := "[namespace ns2: quota exceeded, namespace ns3: conflict]"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestSetReadyConditions(t *testing.T) {
+	status := &replicationv1alpha1.ReplicationStatus{
+		Targets: []replicationv1alpha1.TargetStatus{{Namespace: "ns1", State: replicationv1alpha1.TargetStateSynced}},
+	}
+	setReadyConditions(status, 1)
+	if !meta.IsStatusConditionTrue(status.Conditions, replicationv1alpha1.ConditionReady) ||
+		!meta.IsStatusConditionFalse(status.Conditions, replicationv1alpha1.ConditionDegraded) {
+		t.Errorf("expected Ready and not Degraded, got %+v", status.Conditions)
+	}
+
+	status.Targets = append(status.Targets, replicationv1alpha1.TargetStatus{Namespace: "ns2", State: replicationv1alpha1.TargetStateFailed})
+	setReadyConditions(status, 1)
+	if !meta.IsStatusConditionFalse(status.Conditions, replicationv1alpha1.ConditionReady) ||
+		!meta.IsStatusConditionTrue(status.Conditions, replicationv1alpha1.ConditionDegraded) {
+		t.Errorf("expected Degraded and not Ready, got %+v", status.Conditions)
+	}
+}
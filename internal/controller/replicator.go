@@ -18,13 +18,17 @@ package controller
 
 import (
 	"context"
-	"fmt"
+	"path"
+	"slices"
 	"strings"
-	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
 )
 
 // Shared annotation keys for replication configuration
@@ -32,6 +36,8 @@ const (
 	ReplicateKey       = "replizieren.dev/replicate"
 	ReplicateAllKey    = "replizieren.dev/replicate-all"
 	RolloutOnUpdateKey = "replizieren.dev/rollout-on-update"
+	KeysKey            = "replizieren.dev/keys"
+	ConflictPolicyKey  = "replizieren.dev/conflict-policy"
 )
 
 // ReplicationConfig holds parsed annotation configuration
@@ -40,6 +46,21 @@ type ReplicationConfig struct {
 	ReplicateAll     bool
 	RolloutOnUpdate  bool
 	SkipReplication  bool
+
+	// NamespaceSelectors select additional target namespaces by label.
+	NamespaceSelectors []labels.Selector
+	// ExcludeNamespaces never receive a replica.
+	ExcludeNamespaces []string
+	// Keys limits replication to the listed data keys. Empty replicates all keys.
+	Keys []string
+	// ConflictPolicy defines how unmanaged objects in target namespaces are handled.
+	ConflictPolicy replicationv1alpha1.ConflictPolicy
+	// Waves propagates changes to the target namespaces in order.
+	Waves []Wave
+	// RolloutSelector limits the workloads restarted by RolloutOnUpdate.
+	RolloutSelector RolloutSelector
+	// OnUpdateJob is run in every target namespace whose replica was updated.
+	OnUpdateJob *JobTemplateRef
 }
 
 // ParseReplicationConfig extracts replication settings from annotations.
@@ -58,6 +79,20 @@ func ParseReplicationConfig(annotations map[string]string, sourceNamespace strin
 
 	config := ReplicationConfig{
 		RolloutOnUpdate: rollout,
+		Keys:            SplitList(annotations[KeysKey]),
+		ConflictPolicy:  replicationv1alpha1.ConflictPolicy(annotations[ConflictPolicyKey]),
+	}
+	// Invalid waves are rejected by the webhook, they are ignored here
+	if waves, err := ParseWaves(annotations[WavesKey], annotations[WaveDelayKey]); err == nil {
+		config.Waves = waves
+	}
+	if selector, err := ParseRolloutSelector(annotations[RolloutSelectorKey]); err == nil {
+		config.RolloutSelector = selector
+	}
+	if value, ok := annotations[OnUpdateJobKey]; ok {
+		if ref, err := ParseJobTemplateRef(value); err == nil {
+			config.OnUpdateJob = ref
+		}
 	}
 
 	// Check for replicate-all annotation (takes precedence)
@@ -89,10 +124,10 @@ func ParseReplicationConfig(annotations map[string]string, sourceNamespace strin
 		return config
 	}
 
-	// Parse comma-separated namespace list
-	for _, ns := range strings.Split(replicateTo, ",") {
-		ns = strings.TrimSpace(ns)
-		if ns != "" && ns != sourceNamespace {
+	// Parse comma-separated namespace list. The webhook fails open, so
+	// protected namespaces are dropped here as well
+	for _, ns := range SplitList(replicateTo) {
+		if ns != sourceNamespace && !ProtectedNamespaces[ns] {
 			config.TargetNamespaces = append(config.TargetNamespaces, ns)
 		}
 	}
@@ -100,8 +135,95 @@ func ParseReplicationConfig(annotations map[string]string, sourceNamespace strin
 	return config
 }
 
-// GetAllNamespaces returns all namespace names except the excluded one
-func GetAllNamespaces(ctx context.Context, c client.Client, excludeNamespace string) ([]string, error) {
+// SplitList splits a comma-separated annotation value, trimming whitespace and
+// dropping empty entries.
+func SplitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// IsNamespacePattern returns true if a target namespace entry is a glob
+// pattern as understood by path.Match, e.g. "team-*".
+func IsNamespacePattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// MatchesNamespace returns true if the configuration targets the namespace.
+// Explicitly listed namespaces are always targeted; replicate-all, glob
+// patterns and namespace selectors never match system or protected namespaces.
+func (c ReplicationConfig) MatchesNamespace(ns *corev1.Namespace, sourceNamespace string) bool {
+	if ns.Name == sourceNamespace || slices.Contains(c.ExcludeNamespaces, ns.Name) {
+		return false
+	}
+	if slices.Contains(c.TargetNamespaces, ns.Name) {
+		return true
+	}
+	if IsProtectedNamespace(ns.Name) {
+		return false
+	}
+	if c.ReplicateAll {
+		return true
+	}
+	for _, pattern := range c.TargetNamespaces {
+		if !IsNamespacePattern(pattern) {
+			continue
+		}
+		if matched, _ := path.Match(pattern, ns.Name); matched {
+			return true
+		}
+	}
+	for _, selector := range c.NamespaceSelectors {
+		if selector.Matches(labels.Set(ns.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// rolloutSelector returns the selector of the workloads restarted in the
+// namespace by RolloutOnUpdate, nil if rollout is disabled.
+func (c ReplicationConfig) rolloutSelector(namespace string) labels.Selector {
+	if !c.RolloutOnUpdate {
+		return nil
+	}
+	return c.RolloutSelector.For(namespace)
+}
+
+// ResolveTargetNamespaces returns the existing namespaces a source should be
+// replicated to. Namespaces are only listed when the configuration selects
+// more than an explicit list of names, otherwise each name is looked up.
+// Named namespaces that do not exist yet are left out, they are picked up by
+// the NamespaceReconciler once they are created.
+func ResolveTargetNamespaces(
+	ctx context.Context,
+	c client.Client,
+	config ReplicationConfig,
+	sourceNamespace string,
+) ([]string, error) {
+	if !config.ReplicateAll && len(config.NamespaceSelectors) == 0 &&
+		!slices.ContainsFunc(config.TargetNamespaces, IsNamespacePattern) {
+		var namespaces []string
+		for _, ns := range config.TargetNamespaces {
+			if ns == sourceNamespace || slices.Contains(config.ExcludeNamespaces, ns) {
+				continue
+			}
+			if err := c.Get(ctx, client.ObjectKey{Name: ns}, &corev1.Namespace{}); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			namespaces = append(namespaces, ns)
+		}
+		return namespaces, nil
+	}
+
 	var nsList corev1.NamespaceList
 	if err := c.List(ctx, &nsList); err != nil {
 		return nil, err
@@ -109,76 +231,37 @@ func GetAllNamespaces(ctx context.Context, c client.Client, excludeNamespace str
 
 	var namespaces []string
 	for _, ns := range nsList.Items {
-		if ns.Name != excludeNamespace {
+		if config.MatchesNamespace(&ns, sourceNamespace) {
 			namespaces = append(namespaces, ns.Name)
 		}
 	}
 	return namespaces, nil
 }
 
-// RestartDeploymentsFunc is a function type that checks if a deployment uses a resource
-type RestartDeploymentsFunc func(*appsv1.Deployment) bool
+// GetAllNamespaces returns all namespace names except the excluded one
+func GetAllNamespaces(ctx context.Context, c client.Client, excludeNamespace string) ([]string, error) {
+	var nsList corev1.NamespaceList
+	if err := c.List(ctx, &nsList); err != nil {
+		return nil, err
+	}
 
-// RestartDeployments patches deployments that use the specified resource
-func RestartDeployments(
-	ctx context.Context,
-	c client.Client,
-	namespace string,
-	annotationKey string,
-	isUsing RestartDeploymentsFunc,
-) error {
-	var deploys appsv1.DeploymentList
-	if err := c.List(ctx, &deploys, client.InNamespace(namespace)); err != nil {
-		return err
-	}
-
-	for _, deploy := range deploys.Items {
-		if isUsing(&deploy) {
-			patch := client.MergeFrom(deploy.DeepCopy())
-			if deploy.Spec.Template.Annotations == nil {
-				deploy.Spec.Template.Annotations = map[string]string{}
-			}
-			deploy.Spec.Template.Annotations[annotationKey] = time.Now().Format(time.RFC3339)
-			if err := c.Patch(ctx, &deploy, patch); err != nil {
-				return fmt.Errorf("failed to patch deployment %s: %w", deploy.Name, err)
-			}
+	var namespaces []string
+	for _, ns := range nsList.Items {
+		if ns.Name != excludeNamespace {
+			namespaces = append(namespaces, ns.Name)
 		}
 	}
-	return nil
+	return namespaces, nil
 }
 
 // IsDeploymentUsingSecret checks if a deployment uses the named secret
 func IsDeploymentUsingSecret(deploy *appsv1.Deployment, secretName string) bool {
-	for _, vol := range deploy.Spec.Template.Spec.Volumes {
-		if vol.Secret != nil && vol.Secret.SecretName == secretName {
-			return true
-		}
-	}
-	for _, c := range deploy.Spec.Template.Spec.Containers {
-		for _, envFrom := range c.EnvFrom {
-			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == secretName {
-				return true
-			}
-		}
-	}
-	return false
+	return IsPodSpecUsingSecret(&deploy.Spec.Template.Spec, secretName)
 }
 
 // IsDeploymentUsingConfigMap checks if a deployment uses the named configmap
 func IsDeploymentUsingConfigMap(deploy *appsv1.Deployment, cmName string) bool {
-	for _, vol := range deploy.Spec.Template.Spec.Volumes {
-		if vol.ConfigMap != nil && vol.ConfigMap.Name == cmName {
-			return true
-		}
-	}
-	for _, c := range deploy.Spec.Template.Spec.Containers {
-		for _, envFrom := range c.EnvFrom {
-			if envFrom.ConfigMapRef != nil && envFrom.ConfigMapRef.Name == cmName {
-				return true
-			}
-		}
-	}
-	return false
+	return IsPodSpecUsingConfigMap(&deploy.Spec.Template.Spec, cmName)
 }
 
 // SystemNamespaces contains namespaces that should be excluded from replication
@@ -193,6 +276,18 @@ func IsSystemNamespace(name string) bool {
 	return SystemNamespaces[name]
 }
 
+// ProtectedNamespaces contains namespaces configured with
+// --protected-namespaces. Like system namespaces, they are never matched by
+// replicate-all, glob patterns or selectors and cannot be named by
+// Replications. Unlike system namespaces, annotations cannot name them either.
+var ProtectedNamespaces = map[string]bool{}
+
+// IsProtectedNamespace returns true if the namespace is a system namespace or
+// a configured protected namespace
+func IsProtectedNamespace(name string) bool {
+	return IsSystemNamespace(name) || ProtectedNamespaces[name]
+}
+
 // ShouldReplicateToNamespace checks if a resource should be replicated to a target namespace
 func ShouldReplicateToNamespace(annotations map[string]string, targetNamespace, sourceNamespace string) bool {
 	if targetNamespace == sourceNamespace {
@@ -218,36 +313,88 @@ func ShouldReplicateToNamespace(annotations map[string]string, targetNamespace,
 	return false
 }
 
-// GetSecretsToReplicateAll returns all secrets that have replicate-all or replicate: true annotation
-func GetSecretsToReplicateAll(ctx context.Context, c client.Client) ([]corev1.Secret, error) {
+// listSources returns all Secrets and ConfigMaps that are not replicas or
+// revisions.
+func listSources(ctx context.Context, c client.Client) ([]client.Object, error) {
+	var sources []client.Object
+
 	var secretList corev1.SecretList
 	if err := c.List(ctx, &secretList); err != nil {
 		return nil, err
 	}
+	for i := range secretList.Items {
+		if !IsReplica(&secretList.Items[i]) && !IsRevision(&secretList.Items[i]) {
+			sources = append(sources, &secretList.Items[i])
+		}
+	}
+
+	var cmList corev1.ConfigMapList
+	if err := c.List(ctx, &cmList); err != nil {
+		return nil, err
+	}
+	for i := range cmList.Items {
+		if !IsReplica(&cmList.Items[i]) {
+			sources = append(sources, &cmList.Items[i])
+		}
+	}
+	return sources, nil
+}
 
+// GetSecretsToReplicateAll returns all secrets that have replicate-all or replicate: true annotation
+//
+// Deprecated: Sources may also select namespaces by name, pattern or label.
+// Use ResolveTargetNamespaces to find the namespaces of a source.
+func GetSecretsToReplicateAll(ctx context.Context, c client.Client) ([]corev1.Secret, error) {
+	sources, err := listSources(ctx, c)
+	if err != nil {
+		return nil, err
+	}
 	var result []corev1.Secret
-	for _, secret := range secretList.Items {
-		config := ParseReplicationConfig(secret.Annotations, secret.Namespace)
-		if config.ReplicateAll {
-			result = append(result, secret)
+	for _, source := range sources {
+		if secret, ok := source.(*corev1.Secret); ok && ParseReplicationConfig(secret.Annotations, secret.Namespace).ReplicateAll {
+			result = append(result, *secret)
 		}
 	}
 	return result, nil
 }
 
 // GetConfigMapsToReplicateAll returns all configmaps that have replicate-all or replicate: true annotation
+//
+// Deprecated: Sources may also select namespaces by name, pattern or label.
+// Use ResolveTargetNamespaces to find the namespaces of a source.
 func GetConfigMapsToReplicateAll(ctx context.Context, c client.Client) ([]corev1.ConfigMap, error) {
-	var cmList corev1.ConfigMapList
-	if err := c.List(ctx, &cmList); err != nil {
+	sources, err := listSources(ctx, c)
+	if err != nil {
 		return nil, err
 	}
-
 	var result []corev1.ConfigMap
-	for _, cm := range cmList.Items {
-		config := ParseReplicationConfig(cm.Annotations, cm.Namespace)
-		if config.ReplicateAll {
-			result = append(result, cm)
+	for _, source := range sources {
+		if cm, ok := source.(*corev1.ConfigMap); ok && ParseReplicationConfig(cm.Annotations, cm.Namespace).ReplicateAll {
+			result = append(result, *cm)
 		}
 	}
 	return result, nil
 }
+
+// listReplicas returns all Secret and ConfigMap replicas in the namespace.
+func listReplicas(ctx context.Context, c client.Client, namespace string) ([]client.Object, error) {
+	var replicas []client.Object
+	selector := client.MatchingLabels{ReplicaLabel: "true"}
+
+	var secretList corev1.SecretList
+	if err := c.List(ctx, &secretList, client.InNamespace(namespace), selector); err != nil {
+		return nil, err
+	}
+	for i := range secretList.Items {
+		replicas = append(replicas, &secretList.Items[i])
+	}
+
+	var cmList corev1.ConfigMapList
+	if err := c.List(ctx, &cmList, client.InNamespace(namespace), selector); err != nil {
+		return nil, err
+	}
+	for i := range cmList.Items {
+		replicas = append(replicas, &cmList.Items[i])
+	}
+	return replicas, nil
+}
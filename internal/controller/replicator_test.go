@@ -17,10 +17,14 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"testing"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestParseReplicationConfig_EmptyAnnotations(t *testing.T) {
@@ -229,6 +233,33 @@ func TestParseReplicationConfig_NamespaceNamedTrueWithReplicateAll(t *testing.T)
 	}
 }
 
+func TestGetSourcesToReplicateAll(t *testing.T) {
+	all := map[string]string{ReplicateAllKey: "true"}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "source-ns", Annotations: all}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "named", Namespace: "source-ns",
+			Annotations: map[string]string{ReplicateKey: "team-a"}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "team-a", Annotations: all,
+			Labels: map[string]string{ReplicaLabel: "true"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "all", Namespace: "source-ns", Annotations: all}},
+	).Build()
+
+	secrets, err := GetSecretsToReplicateAll(context.Background(), c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(secrets) != 1 || secrets[0].Name != "all" || secrets[0].Namespace != "source-ns" {
+		t.Errorf("expected only the source Secret replicated to all namespaces, got %v", secrets)
+	}
+	configMaps, err := GetConfigMapsToReplicateAll(context.Background(), c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(configMaps) != 1 || configMaps[0].Name != "all" {
+		t.Errorf("expected the ConfigMap replicated to all namespaces, got %v", configMaps)
+	}
+}
+
 func TestIsDeploymentUsingSecret_EnvFrom(t *testing.T) {
 	deploy := &appsv1.Deployment{
 		Spec: appsv1.DeploymentSpec{
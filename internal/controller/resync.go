@@ -0,0 +1,242 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// ResyncSummary counts the outcomes of a resync pass.
+type ResyncSummary struct {
+	Sources   int
+	Created   int
+	Updated   int
+	Restored  int
+	Unchanged int
+	Conflicts int
+	Pending   int
+	Failed    int
+}
+
+// add counts the target results of one source.
+func (s *ResyncSummary) add(results map[string]targetResult) {
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			s.Failed++
+		case result.Outcome == ReplicaCreated:
+			s.Created++
+		case result.Outcome == ReplicaUpdated:
+			s.Updated++
+		case result.Outcome == ReplicaRestored:
+			s.Restored++
+		case result.Outcome == ReplicaConflict:
+			s.Conflicts++
+		case result.Outcome == ReplicaPending:
+			s.Pending++
+		default:
+			s.Unchanged++
+		}
+	}
+}
+
+// Resyncer periodically re-evaluates every Secret and ConfigMap, so replicas
+// converge even if an event was missed or a target could not be written.
+// Sources are enqueued into the Secret and ConfigMap reconcilers, so a resync
+// never syncs a source concurrently with its events.
+type Resyncer struct {
+	client.Client
+	Pause  *Pause
+	Period time.Duration
+
+	mu         sync.Mutex
+	secrets    chan event.GenericEvent
+	configMaps chan event.GenericEvent
+	// pending holds the resource versions of the sources of the current pass
+	// that were not synced yet
+	pending map[string]string
+	started time.Time
+	summary ResyncSummary
+	// last is the summary of the last finished pass
+	last ResyncSummary
+}
+
+// Start runs a resync pass every period until the context is cancelled.
+func (r *Resyncer) Start(ctx context.Context) error {
+	ctx = log.IntoContext(ctx, ctrl.Log.WithName("resync"))
+	// The first pass is left to the reconcilers, which process every object on startup
+	ticker := time.NewTicker(r.Period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.Resync(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection ensures only the leader resyncs.
+func (r *Resyncer) NeedLeaderElection() bool {
+	return true
+}
+
+// watchSource returns the resync requests of sources of the kind, which are
+// watched by their reconciler.
+func (r *Resyncer) watchSource(kind replicationv1alpha1.SourceKind) source.Source {
+	return source.Channel(r.events(kind), &handler.EnqueueRequestForObject{})
+}
+
+// events returns the channel resync requests of sources of the kind are sent to.
+func (r *Resyncer) events(kind replicationv1alpha1.SourceKind) chan event.GenericEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.secrets == nil {
+		r.secrets = make(chan event.GenericEvent)
+		r.configMaps = make(chan event.GenericEvent)
+	}
+	if kind == replicationv1alpha1.SourceKindConfigMap {
+		return r.configMaps
+	}
+	return r.secrets
+}
+
+// Resync enqueues every source once. The summary is recorded once the
+// reconcilers synced all of them.
+func (r *Resyncer) Resync(ctx context.Context) {
+	logger := log.FromContext(ctx)
+
+	paused, message, err := r.Pause.GloballyPaused(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to check for paused replication, skipping resync")
+		return
+	}
+	if paused {
+		logger.Info("Replication paused, skipping resync", "reason", message)
+		return
+	}
+
+	sources, err := listSources(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to list sources, skipping resync")
+		return
+	}
+
+	r.mu.Lock()
+	if len(r.pending) > 0 {
+		logger.Info("Previous resync did not finish, starting over", "pending", len(r.pending))
+	}
+	r.pending = make(map[string]string, len(sources))
+	for _, source := range sources {
+		r.pending[resyncKey(SourceKindOf(source), client.ObjectKeyFromObject(source))] = source.GetResourceVersion()
+	}
+	r.started, r.summary = time.Now(), ResyncSummary{}
+	if len(sources) == 0 {
+		r.finish()
+	}
+	r.mu.Unlock()
+
+	for _, source := range sources {
+		select {
+		case r.events(SourceKindOf(source)) <- event.GenericEvent{Object: source}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// take returns whether the current pass waits for the source and whether it
+// is synced as resync, i.e. did not change since it was enqueued.
+func (r *Resyncer) take(source client.Object) (pending, resync bool) {
+	if r == nil {
+		return false, false
+	}
+	key := resyncKey(SourceKindOf(source), client.ObjectKeyFromObject(source))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	version, pending := r.pending[key]
+	return pending, pending && version == source.GetResourceVersion()
+}
+
+// record adds the outcome of a source taken from the current pass.
+func (r *Resyncer) record(source client.Object, results map[string]targetResult, err error) {
+	if r == nil {
+		return
+	}
+	key := resyncKey(SourceKindOf(source), client.ObjectKeyFromObject(source))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[key]; !ok {
+		return
+	}
+	delete(r.pending, key)
+	switch {
+	case err != nil:
+		r.summary.Failed++
+	case len(results) > 0:
+		r.summary.Sources++
+		r.summary.add(results)
+	}
+	if len(r.pending) == 0 {
+		r.finish()
+	}
+}
+
+// forget drops a deleted source from the current pass.
+func (r *Resyncer) forget(kind replicationv1alpha1.SourceKind, key types.NamespacedName) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[resyncKey(kind, key)]; !ok {
+		return
+	}
+	delete(r.pending, resyncKey(kind, key))
+	if len(r.pending) == 0 {
+		r.finish()
+	}
+}
+
+// finish records the summary of the current pass. The caller holds the lock.
+func (r *Resyncer) finish() {
+	summary, duration := r.summary, time.Since(r.started)
+	r.last = summary
+	recordResync(summary, duration)
+	ctrl.Log.WithName("resync").Info("Resync finished", "duration", duration, "sources", summary.Sources,
+		"created", summary.Created, "updated", summary.Updated, "restored", summary.Restored,
+		"unchanged", summary.Unchanged, "conflicts", summary.Conflicts, "pending", summary.Pending, "failed", summary.Failed)
+}
+
+// resyncKey identifies a source in a resync pass.
+func resyncKey(kind replicationv1alpha1.SourceKind, key types.NamespacedName) string {
+	return fmt.Sprintf("%s/%s/%s", kind, key.Namespace, key.Name)
+}
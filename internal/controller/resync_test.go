@@ -0,0 +1,126 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestResync_RepairsReplicas(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	_ = replicationv1alpha1.AddToScheme(testScheme)
+
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "creds",
+			Namespace:   "source-ns",
+			Annotations: map[string]string{ReplicateKey: "ns-a, ns-b, ns-c"},
+		},
+		Data: map[string][]byte{"key": []byte("shared")},
+	}
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		source,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "source-ns"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-b"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-c"}},
+	).Build()
+
+	config := ParseReplicationConfig(source.Annotations, source.Namespace)
+	for _, ns := range []string{"ns-a", "ns-b"} {
+		if _, err := ReplicateObject(ctx, c, source, ns, config); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Simulate a missed update event on ns-b
+	var replica corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Name: "creds", Namespace: "ns-b"}, &replica); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replica.Data["key"] = []byte("stale")
+	if err := c.Update(ctx, &replica); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resyncer := &Resyncer{Client: c}
+	reconciler := &SecretReconciler{Client: c, Scheme: testScheme, SyncOptions: SyncOptions{Resyncer: resyncer}}
+	// resync runs a pass and reconciles the secrets it enqueues
+	resync := func() ResyncSummary {
+		t.Helper()
+		go resyncer.Resync(ctx)
+		e := <-resyncer.events(replicationv1alpha1.SourceKindSecret)
+		if _, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(e.Object)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resyncer.mu.Lock()
+		defer resyncer.mu.Unlock()
+		if len(resyncer.pending) > 0 {
+			t.Fatalf("expected the pass to finish, %d sources pending", len(resyncer.pending))
+		}
+		return resyncer.last
+	}
+
+	summary := resync()
+	expected := ResyncSummary{Sources: 1, Created: 1, Restored: 1, Unchanged: 1}
+	if summary != expected {
+		t.Errorf("expected %+v, got %+v", expected, summary)
+	}
+
+	summary = resync()
+	expected = ResyncSummary{Sources: 1, Unchanged: 3}
+	if summary != expected {
+		t.Errorf("expected second pass to write nothing, got %+v", summary)
+	}
+}
+
+func TestResyncer_Take(t *testing.T) {
+	source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns", ResourceVersion: "1"}}
+	resyncer := &Resyncer{pending: map[string]string{"Secret/source-ns/creds": "1"}}
+
+	if pending, resync := resyncer.take(source); !pending || !resync {
+		t.Errorf("expected an unchanged source to be synced as resync, got pending %v resync %v", pending, resync)
+	}
+
+	// A change that arrives before the resync request is synced as a change
+	source.ResourceVersion = "2"
+	if pending, resync := resyncer.take(source); !pending || resync {
+		t.Errorf("expected a changed source to not be synced as resync, got pending %v resync %v", pending, resync)
+	}
+
+	resyncer.record(source, nil, nil)
+	if pending, _ := resyncer.take(source); pending {
+		t.Error("expected the recorded source to no longer be pending")
+	}
+	if pending, _ := (*Resyncer)(nil).take(source); pending {
+		t.Error("expected no source to be pending without resync")
+	}
+}
@@ -0,0 +1,426 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// RollbackKey on a source restores the payload of a previous revision, e.g.
+// "3", or "previous" for the last revision with different content. The
+// annotation is removed once the source was restored.
+const RollbackKey = "replizieren.dev/rollback"
+
+// RollbackPrevious selects the last revision whose content differs from the
+// current content of the source.
+const RollbackPrevious = "previous"
+
+// Revision bookkeeping keys
+const (
+	// RevisionLabel records the number of a revision.
+	RevisionLabel = "replizieren.dev/revision"
+	// RevisionKindLabel records the lower-case kind of the source of a revision.
+	RevisionKindLabel = "replizieren.dev/revision-kind"
+	// RevisionNamespaceLabel records the namespace of the source of a revision.
+	RevisionNamespaceLabel = "replizieren.dev/revision-namespace"
+	// RevisionNameLabel records the name of the source of a revision, shortened
+	// to fit into a label value.
+	RevisionNameLabel = "replizieren.dev/revision-name"
+	// revisionBinaryKeysAnnotation lists the binaryData keys of a ConfigMap
+	// revision, so they are restored as binary data.
+	revisionBinaryKeysAnnotation = "replizieren.dev/binary-keys"
+)
+
+// RevisionSecretType is the type of the Secrets that hold revisions.
+const RevisionSecretType corev1.SecretType = "replizieren.dev/revision"
+
+// Event reasons for rollbacks
+const (
+	reasonRolledBack     = "RolledBack"
+	reasonRollbackFailed = "RollbackFailed"
+)
+
+// RevisionHistory keeps the last revisions of the replicated payload of every
+// source. Revisions are stored as Secrets in Namespace, also for ConfigMaps,
+// so the content of Secrets is never readable with access to ConfigMaps. A nil
+// RevisionHistory, an empty Namespace or a Limit of 0 keeps no revisions.
+type RevisionHistory struct {
+	// Namespace is the namespace of the operator.
+	Namespace string
+	// Limit is the number of revisions kept per source.
+	Limit int
+}
+
+// enabled returns true if revisions are kept.
+func (h *RevisionHistory) enabled() bool {
+	return h != nil && h.Namespace != "" && h.Limit > 0
+}
+
+// IsRevision returns true if the object holds a revision of a source.
+func IsRevision(obj client.Object) bool {
+	_, ok := obj.GetLabels()[RevisionLabel]
+	return ok
+}
+
+// ParseRollback parses the rollback annotation. It returns 0 for "previous".
+func ParseRollback(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == RollbackPrevious {
+		return 0, nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, fmt.Errorf("invalid revision %q: expected a positive number or %q", value, RollbackPrevious)
+	}
+	return revision, nil
+}
+
+// revisionNumber returns the number of a revision, 0 if it has none.
+func revisionNumber(revision *corev1.Secret) int64 {
+	n, _ := strconv.ParseInt(revision.Labels[RevisionLabel], 10, 64)
+	return n
+}
+
+// revisionLabels returns the labels that select the revisions of the source.
+func revisionLabels(kind replicationv1alpha1.SourceKind, key types.NamespacedName) client.MatchingLabels {
+	name := key.Name
+	if len(name) > validation.LabelValueMaxLength {
+		sum := sha256.Sum256([]byte(name))
+		suffix := "-" + hex.EncodeToString(sum[:])[:10]
+		name = strings.TrimRight(name[:validation.LabelValueMaxLength-len(suffix)], "-.") + suffix
+	}
+	return client.MatchingLabels{
+		RevisionKindLabel:      strings.ToLower(string(kind)),
+		RevisionNamespaceLabel: key.Namespace,
+		RevisionNameLabel:      name,
+	}
+}
+
+// revisionName returns the name of the revision of the source with the
+// content hash, so every content is stored once.
+func revisionName(kind replicationv1alpha1.SourceKind, key types.NamespacedName, hash string) string {
+	sum := sha256.Sum256([]byte(string(kind) + "/" + key.String() + "/" + hash))
+	suffix := "-" + hex.EncodeToString(sum[:])[:10]
+	name := strings.ToLower(string(kind)) + "-" + key.Namespace + "-" + key.Name
+	if len(name)+len(suffix) > validation.DNS1123SubdomainMaxLength {
+		name = name[:validation.DNS1123SubdomainMaxLength-len(suffix)]
+	}
+	return strings.TrimRight(name, "-.") + suffix
+}
+
+// listRevisions returns the revisions of the source, oldest first.
+func (h *RevisionHistory) listRevisions(
+	ctx context.Context,
+	c client.Client,
+	kind replicationv1alpha1.SourceKind,
+	key types.NamespacedName,
+) ([]corev1.Secret, error) {
+	var list corev1.SecretList
+	if err := c.List(ctx, &list, client.InNamespace(h.Namespace), revisionLabels(kind, key)); err != nil {
+		return nil, err
+	}
+	revisions := make([]corev1.Secret, 0, len(list.Items))
+	for _, revision := range list.Items {
+		// Shortened names may be shared by several sources
+		if revision.Annotations[SourceAnnotation] == key.String() {
+			revisions = append(revisions, revision)
+		}
+	}
+	slices.SortFunc(revisions, func(a, b corev1.Secret) int {
+		return cmp.Compare(revisionNumber(&a), revisionNumber(&b))
+	})
+	return revisions, nil
+}
+
+// record stores the replicated payload of the source as its latest revision
+// and removes the revisions beyond the limit. Content that is already stored
+// becomes the latest revision again, so a rollback does not duplicate it.
+func (h *RevisionHistory) record(ctx context.Context, c client.Client, source client.Object, config ReplicationConfig) error {
+	kind := SourceKindOf(source)
+	key := client.ObjectKeyFromObject(source)
+	revisions, err := h.listRevisions(ctx, c, kind, key)
+	if err != nil {
+		return err
+	}
+
+	payload := newReplica(source, h.Namespace, config)
+	hash := PayloadHash(payload)
+	var latest int64
+	if len(revisions) > 0 {
+		last := &revisions[len(revisions)-1]
+		if last.Annotations[HashAnnotation] == hash {
+			return nil
+		}
+		latest = revisionNumber(last)
+	}
+	number := strconv.FormatInt(latest+1, 10)
+
+	existing := slices.IndexFunc(revisions, func(r corev1.Secret) bool { return r.Annotations[HashAnnotation] == hash })
+	if existing >= 0 {
+		revision := revisions[existing]
+		revision.Labels[RevisionLabel] = number
+		if err := c.Update(ctx, &revision); err != nil {
+			return err
+		}
+		revisions = append(slices.Delete(revisions, existing, existing+1), revision)
+	} else {
+		revision := newRevision(payload, kind, key, hash)
+		revision.Labels[RevisionLabel] = number
+		if err := c.Create(ctx, revision); err != nil {
+			// The cache has not seen a revision recorded by an earlier sync yet
+			if errors.IsAlreadyExists(err) {
+				return nil
+			}
+			return err
+		}
+		revisions = append(revisions, *revision)
+	}
+	log.FromContext(ctx).Info("Recorded revision", "revision", number)
+
+	for i := 0; i < len(revisions)-h.Limit; i++ {
+		if err := c.Delete(ctx, &revisions[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRevision builds the Secret that holds the payload of a replica.
+func newRevision(payload client.Object, kind replicationv1alpha1.SourceKind, key types.NamespacedName, hash string) *corev1.Secret {
+	revision := &corev1.Secret{Type: RevisionSecretType, Data: map[string][]byte{}}
+	revision.Name = revisionName(kind, key, hash)
+	revision.Namespace = payload.GetNamespace()
+	revision.Labels = revisionLabels(kind, key)
+	revision.Annotations = map[string]string{
+		SourceAnnotation: key.String(),
+		HashAnnotation:   hash,
+	}
+	switch o := payload.(type) {
+	case *corev1.Secret:
+		for k, v := range o.Data {
+			revision.Data[k] = v
+		}
+	case *corev1.ConfigMap:
+		for k, v := range o.Data {
+			revision.Data[k] = []byte(v)
+		}
+		binaryKeys := make([]string, 0, len(o.BinaryData))
+		for k, v := range o.BinaryData {
+			revision.Data[k] = v
+			binaryKeys = append(binaryKeys, k)
+		}
+		if len(binaryKeys) > 0 {
+			slices.Sort(binaryKeys)
+			revision.Annotations[revisionBinaryKeysAnnotation] = strings.Join(binaryKeys, ",")
+		}
+	}
+	return revision
+}
+
+// restoreRevision replaces the replicated keys of the source with the payload
+// of the revision. Keys that are not replicated are kept.
+func restoreRevision(source client.Object, revision *corev1.Secret, keys []string) {
+	replicated := func(k string) bool {
+		return len(keys) == 0 || slices.Contains(keys, k)
+	}
+	switch o := source.(type) {
+	case *corev1.Secret:
+		data := map[string][]byte{}
+		for k, v := range o.Data {
+			if !replicated(k) {
+				data[k] = v
+			}
+		}
+		for k, v := range revision.Data {
+			data[k] = v
+		}
+		o.Data = data
+	case *corev1.ConfigMap:
+		binary := SplitList(revision.Annotations[revisionBinaryKeysAnnotation])
+		data, binaryData := map[string]string{}, map[string][]byte{}
+		for k, v := range o.Data {
+			if !replicated(k) {
+				data[k] = v
+			}
+		}
+		for k, v := range o.BinaryData {
+			if !replicated(k) {
+				binaryData[k] = v
+			}
+		}
+		for k, v := range revision.Data {
+			if slices.Contains(binary, k) {
+				binaryData[k] = v
+			} else {
+				data[k] = string(v)
+			}
+		}
+		o.Data, o.BinaryData = data, binaryData
+	}
+}
+
+// findRevision returns the revision the rollback annotation asks for.
+func findRevision(revisions []corev1.Secret, requested int64, currentHash string) (*corev1.Secret, error) {
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := &revisions[i]
+		if requested == 0 && revision.Annotations[HashAnnotation] != currentHash {
+			return revision, nil
+		}
+		if requested != 0 && revisionNumber(revision) == requested {
+			return revision, nil
+		}
+	}
+	if requested == 0 {
+		return nil, fmt.Errorf("no previous revision")
+	}
+	return nil, fmt.Errorf("revision %d not found", requested)
+}
+
+// rollback restores the revision requested by the rollback annotation of the
+// source and removes the annotation. The restored source is then replicated
+// and rolled out like any other change. A rollback that fails is recorded as
+// an event and not retried. Dry runs only record the rollback they would make.
+func (s *sourceSyncer) rollback(ctx context.Context, writer client.Client, source client.Object, config ReplicationConfig) error {
+	logger := log.FromContext(ctx)
+	annotations := source.GetAnnotations()
+	value := annotations[RollbackKey]
+
+	revision, err := s.requestedRevision(ctx, source, config, value)
+	if s.DryRun || IsDryRun(source) {
+		// Dry runs keep the content and the annotation, the rollback is
+		// applied once the dry run ends
+		if err != nil {
+			logger.Info("Dry run, rollback would fail", "revision", value, "reason", err.Error())
+			if s.Recorder != nil {
+				s.Recorder.Eventf(source, nil, corev1.EventTypeNormal, reasonDryRun, "Rollback",
+					"Dry run, rollback to %s would fail: %v", value, err)
+			}
+			return nil
+		}
+		logger.Info("Dry run, would roll back", "revision", revisionNumber(revision))
+		if s.Recorder != nil {
+			s.Recorder.Eventf(source, nil, corev1.EventTypeNormal, reasonDryRun, "Rollback",
+				"Dry run, would roll back to revision %d", revisionNumber(revision))
+		}
+		return nil
+	}
+	if err == nil {
+		restoreRevision(source, revision, config.Keys)
+	}
+	delete(annotations, RollbackKey)
+	source.SetAnnotations(annotations)
+	if updateErr := writer.Update(ctx, source); updateErr != nil {
+		return updateErr
+	}
+
+	if err != nil {
+		logger.Info("Rollback failed", "revision", value, "reason", err.Error())
+		if s.Recorder != nil {
+			s.Recorder.Eventf(source, nil, corev1.EventTypeWarning, reasonRollbackFailed, "Rollback",
+				"Rollback to %s failed: %v", value, err)
+		}
+		return nil
+	}
+	logger.Info("Rolled back", "revision", revisionNumber(revision))
+	if s.Recorder != nil {
+		s.Recorder.Eventf(source, nil, corev1.EventTypeNormal, reasonRolledBack, "Rollback",
+			"Rolled back to revision %d", revisionNumber(revision))
+	}
+	return nil
+}
+
+// requestedRevision looks up the revision named by the rollback annotation.
+func (s *sourceSyncer) requestedRevision(
+	ctx context.Context,
+	source client.Object,
+	config ReplicationConfig,
+	value string,
+) (*corev1.Secret, error) {
+	requested, err := ParseRollback(value)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Revisions.enabled() {
+		return nil, fmt.Errorf("revision history is disabled")
+	}
+	revisions, err := s.Revisions.listRevisions(ctx, s.Client, SourceKindOf(source), client.ObjectKeyFromObject(source))
+	if err != nil {
+		return nil, err
+	}
+	return findRevision(revisions, requested, PayloadHash(newReplica(source, s.Revisions.Namespace, config)))
+}
+
+// recordRevision stores the current payload of the source. Failures are
+// logged, as the history must not block replication.
+func (s *sourceSyncer) recordRevision(ctx context.Context, source client.Object, config ReplicationConfig) {
+	if !s.Revisions.enabled() || s.DryRun || IsDryRun(source) {
+		return
+	}
+	if err := s.Revisions.record(ctx, s.Client, source, config); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to record revision")
+	}
+}
+
+// revisionHandler removes the revisions of sources when they are deleted.
+// Failures are logged, as the source is gone and cannot be retried.
+func (s *sourceSyncer) revisionHandler() handler.EventHandler {
+	return handler.Funcs{
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if err := s.deleteRevisions(ctx, SourceKindOf(e.Object), client.ObjectKeyFromObject(e.Object)); err != nil {
+				log.FromContext(ctx).Error(err, "Failed to delete revisions",
+					"namespace", e.Object.GetNamespace(), "name", e.Object.GetName())
+			}
+		},
+	}
+}
+
+// deleteRevisions removes the revisions of a deleted source.
+func (s *sourceSyncer) deleteRevisions(ctx context.Context, kind replicationv1alpha1.SourceKind, key types.NamespacedName) error {
+	if !s.Revisions.enabled() || s.DryRun {
+		return nil
+	}
+	revisions, err := s.Revisions.listRevisions(ctx, s.Client, kind, key)
+	if err != nil {
+		return err
+	}
+	for i := range revisions {
+		if err := s.Delete(ctx, &revisions[i]); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
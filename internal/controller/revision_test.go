@@ -0,0 +1,196 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestParseRollback(t *testing.T) {
+	if revision, err := ParseRollback("3"); err != nil || revision != 3 {
+		t.Errorf("expected revision 3, got %d (%v)", revision, err)
+	}
+	if revision, err := ParseRollback("previous"); err != nil || revision != 0 {
+		t.Errorf("expected previous revision, got %d (%v)", revision, err)
+	}
+	for _, value := range []string{"", "0", "-1", "last"} {
+		if _, err := ParseRollback(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestRestoreRevision_ConfigMap(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-config", Namespace: "source-ns"},
+		Data:       map[string]string{"config.yaml": "v1", "local": "kept"},
+		BinaryData: map[string][]byte{"logo.png": {1}},
+	}
+	config := ReplicationConfig{Keys: []string{"config.yaml", "logo.png"}}
+	revision := newRevision(newReplica(source, "replizieren-system", config), replicationv1alpha1.SourceKindConfigMap,
+		client.ObjectKeyFromObject(source), "hash")
+	if _, ok := revision.Data["local"]; ok {
+		t.Error("expected revision to only hold replicated keys")
+	}
+
+	source.Data = map[string]string{"config.yaml": "v2", "local": "changed"}
+	source.BinaryData = nil
+	restoreRevision(source, revision, config.Keys)
+	if source.Data["config.yaml"] != "v1" || source.Data["local"] != "changed" {
+		t.Errorf("expected replicated keys to be restored and others kept, got %v", source.Data)
+	}
+	if string(source.BinaryData["logo.png"]) != string([]byte{1}) {
+		t.Errorf("expected binary data to be restored, got %v", source.BinaryData)
+	}
+}
+
+func TestSyncSource_RevisionHistoryAndRollback(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey:       "target-ns",
+				RolloutOnUpdateKey: "true",
+			},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "target-ns"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	f := newSyncFixture(t, source, deploy)
+	history := &RevisionHistory{Namespace: "replizieren-system", Limit: 2}
+	f.syncer.Revisions = history
+
+	sync := func() {
+		t.Helper()
+		f.get(source)
+		f.sync(source)
+	}
+	update := func(password string) {
+		t.Helper()
+		source.Data["password"] = []byte(password)
+		f.update(source)
+		sync()
+	}
+	revisions := func() map[int64]string {
+		t.Helper()
+		list, err := history.listRevisions(f.ctx, f.client, replicationv1alpha1.SourceKindSecret, client.ObjectKeyFromObject(source))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		result := map[int64]string{}
+		for i := range list {
+			result[revisionNumber(&list[i])] = string(list[i].Data["password"])
+		}
+		return result
+	}
+	replicaData := func() string {
+		return f.secretData("target-ns", "creds", "password")
+	}
+
+	sync()
+	sync()
+	if got := revisions(); len(got) != 1 || got[1] != "v1" {
+		t.Fatalf("expected one revision, got %v", got)
+	}
+	update("v2")
+	update("v3")
+	if got := revisions(); len(got) != 2 || got[2] != "v2" || got[3] != "v3" {
+		t.Fatalf("expected the last two revisions, got %v", got)
+	}
+
+	checksum := f.checksum(source, "target-ns", "app")
+
+	source.Annotations[RollbackKey] = RollbackPrevious
+	f.update(source)
+	sync()
+	f.get(source)
+	if string(source.Data["password"]) != "v2" || replicaData() != "v2" {
+		t.Errorf("expected source and replica to be rolled back, got %q and %q", source.Data["password"], replicaData())
+	}
+	if _, ok := source.Annotations[RollbackKey]; ok {
+		t.Error("expected rollback annotation to be removed")
+	}
+	if got := revisions(); len(got) != 2 || got[3] != "v3" || got[4] != "v2" {
+		t.Errorf("expected the restored content to become the latest revision, got %v", got)
+	}
+	if f.checksum(source, "target-ns", "app") == checksum {
+		t.Error("expected the rollback to restart the workloads")
+	}
+
+	// Pruned revisions cannot be restored
+	source.Annotations[RollbackKey] = "1"
+	f.update(source)
+	sync()
+	f.get(source)
+	if _, ok := source.Annotations[RollbackKey]; ok || string(source.Data["password"]) != "v2" {
+		t.Error("expected a failed rollback to remove the annotation and keep the content")
+	}
+
+	f.syncer.revisionHandler().Delete(f.ctx, event.DeleteEvent{Object: source}, nil)
+	if got := revisions(); len(got) != 0 {
+		t.Errorf("expected revisions of a deleted source to be removed, got %v", got)
+	}
+}
+
+func TestSyncSource_RollbackDryRun(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "creds",
+			Namespace:   "source-ns",
+			Annotations: map[string]string{ReplicateKey: "target-ns"},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	f := newSyncFixture(t, source, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "target-ns"}})
+	f.syncer.Revisions = &RevisionHistory{Namespace: "replizieren-system", Limit: 2}
+	f.sync(source)
+	f.get(source)
+	source.Data["password"] = []byte("v2")
+	f.update(source)
+	f.sync(source)
+	for len(f.recorder.Events) > 0 {
+		<-f.recorder.Events
+	}
+
+	// A dry run only reports the rollback
+	f.get(source)
+	source.Annotations[DryRunKey] = "true"
+	source.Annotations[RollbackKey] = RollbackPrevious
+	f.update(source)
+	f.sync(source)
+	f.get(source)
+	if string(source.Data["password"]) != "v2" || f.secretData("target-ns", "creds", "password") != "v2" {
+		t.Errorf("expected source and replica to keep their content, got %q and %q",
+			source.Data["password"], f.secretData("target-ns", "creds", "password"))
+	}
+	if _, ok := source.Annotations[RollbackKey]; !ok {
+		t.Error("expected the rollback annotation to be kept")
+	}
+	f.expectEvents("Normal DryRun Dry run, would roll back to revision 1")
+}
@@ -0,0 +1,371 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// Annotations that override the rollout scheduler defaults for a single source.
+const (
+	RolloutIntervalKey  = "replizieren.dev/rollout-interval"
+	RolloutWaitReadyKey = "replizieren.dev/rollout-wait-ready"
+)
+
+// defaultRolloutPollInterval is the interval in which restarted workloads are
+// checked for readiness.
+const defaultRolloutPollInterval = 2 * time.Second
+
+// rolloutStep restarts the workloads in one namespace.
+type rolloutStep struct {
+	Namespace  string
+	ChecksumOf RestartWorkloadsFunc
+}
+
+// rolloutPlan restarts the workloads that consume a source, one namespace
+// after the other.
+type rolloutPlan struct {
+	// Source identifies the source, e.g. "Secret/default/creds".
+	Source string
+	// Object is the source itself. Restarted workloads are recorded with an
+	// event referring to it if Recorder is set, which is never the case for
+	// dry runs.
+	Object   client.Object
+	Recorder events.EventRecorder
+	// Client writes the restarts, it may be a dry run client.
+	Client        client.Client
+	Kinds         []WorkloadKind
+	AnnotationKey string
+	Steps         []rolloutStep
+	// Checksum identifies the content and the steps of the plan. Scheduling
+	// a plan with the checksum of the rollout in progress keeps it running.
+	Checksum string
+	// Seed records the checksum on workloads that never carried one instead
+	// of restarting them, see RestartWorkloads.
+	Seed bool
+	// Tracker records the restarted workloads for verification if set,
+	// which is never the case for dry runs.
+	Tracker *RolloutTracker
+}
+
+// run restarts the workloads of all steps immediately.
+func (p *rolloutPlan) run(ctx context.Context) {
+	for _, step := range p.Steps {
+		p.restart(ctx, step)
+	}
+}
+
+// restart restarts the workloads of a single step and returns the restarted
+// workloads. Errors are logged, as they must not fail replication.
+func (p *rolloutPlan) restart(ctx context.Context, step rolloutStep) []Workload {
+	restarted, err := RestartWorkloads(ctx, p.Client, step.Namespace, p.Kinds, p.AnnotationKey, step.ChecksumOf, p.Seed)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to restart workloads", "source", p.Source, "namespace", step.Namespace)
+	}
+	recordRestarts(p.Recorder, p.Object, restarted)
+	p.Tracker.restarted(p.Source, p.AnnotationKey, restarted, time.Now())
+	return restarted
+}
+
+// ChecksumSeeder remembers the sources synced since the operator started. On
+// the first sync of a source, workloads that never carried its checksum are
+// seeded instead of restarted, so upgrading from a version without checksums
+// does not restart every workload that consumes a source.
+type ChecksumSeeder struct {
+	mu     sync.Mutex
+	synced map[string]bool
+}
+
+// firstSync returns true the first time it is called for a source. It is
+// always false for a nil seeder.
+func (s *ChecksumSeeder) firstSync(source client.Object) bool {
+	if s == nil {
+		return false
+	}
+	key := seedKey(SourceKindOf(source), client.ObjectKeyFromObject(source))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.synced[key] {
+		return false
+	}
+	if s.synced == nil {
+		s.synced = map[string]bool{}
+	}
+	s.synced[key] = true
+	return true
+}
+
+// forget drops a deleted source, so a source created again with the same name
+// restarts the workloads that consume it.
+func (s *ChecksumSeeder) forget(kind replicationv1alpha1.SourceKind, key types.NamespacedName) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.synced, seedKey(kind, key))
+}
+
+func seedKey(kind replicationv1alpha1.SourceKind, key types.NamespacedName) string {
+	return string(kind) + "/" + key.String()
+}
+
+// RolloutScheduler restarts workloads in the background, so a source that is
+// consumed in many namespaces does not restart all of them at once. The
+// number of namespaces restarted at the same time is limited cluster-wide,
+// consecutive namespaces of a source are spaced by an interval, and the
+// scheduler can wait for restarted workloads to become ready before moving
+// on. A new rollout of a source supersedes its rollout in progress.
+type RolloutScheduler struct {
+	client.Client
+
+	// MaxConcurrency limits the namespaces restarted at the same time across
+	// all sources. Zero means no limit.
+	MaxConcurrency int
+	// Interval is the default pause between the namespaces of a source.
+	Interval time.Duration
+	// WaitReady makes the scheduler wait by default until restarted workloads
+	// are ready before restarting the next namespace.
+	WaitReady bool
+	// Timeout limits the wait for restarted workloads to become ready.
+	Timeout time.Duration
+
+	pollInterval time.Duration
+
+	once    sync.Once
+	ctx     context.Context
+	cancel  context.CancelFunc
+	slots   chan struct{}
+	mu      sync.Mutex
+	running map[string]scheduledRollout
+	wg      sync.WaitGroup
+}
+
+// scheduledRollout is a rollout running in the background.
+type scheduledRollout struct {
+	cancel   context.CancelFunc
+	checksum string
+}
+
+// init prepares the scheduler on first use, which may be before Start.
+func (r *RolloutScheduler) init() {
+	r.once.Do(func() {
+		r.ctx, r.cancel = context.WithCancel(log.IntoContext(context.Background(), ctrl.Log.WithName("rollout")))
+		if r.MaxConcurrency > 0 {
+			r.slots = make(chan struct{}, r.MaxConcurrency)
+		}
+		if r.pollInterval == 0 {
+			r.pollInterval = defaultRolloutPollInterval
+		}
+		r.running = map[string]scheduledRollout{}
+	})
+}
+
+// Start runs until the context is cancelled and then stops all rollouts.
+func (r *RolloutScheduler) Start(ctx context.Context) error {
+	r.init()
+	<-ctx.Done()
+	r.cancel()
+	r.wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection makes only the leader restart workloads.
+func (r *RolloutScheduler) NeedLeaderElection() bool {
+	return true
+}
+
+// rolloutOptions returns the interval and readiness wait of a source, which
+// may be overridden by annotations. Invalid overrides are ignored.
+func (r *RolloutScheduler) rolloutOptions(source client.Object) (time.Duration, bool) {
+	interval, waitReady := r.Interval, r.WaitReady
+	annotations := source.GetAnnotations()
+	if value, ok := annotations[RolloutIntervalKey]; ok {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			interval = d
+		}
+	}
+	switch annotations[RolloutWaitReadyKey] {
+	case "true":
+		waitReady = true
+	case "false":
+		waitReady = false
+	}
+	return interval, waitReady
+}
+
+// Schedule starts the rollout of a source in the background, cancelling a
+// rollout of the same source still in progress. A plan with the checksum of
+// the rollout in progress leaves it running, so syncs that do not change the
+// plan do not start the sequence over.
+func (r *RolloutScheduler) Schedule(source client.Object, plan *rolloutPlan) {
+	r.init()
+	interval, waitReady := r.rolloutOptions(source)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if running, ok := r.running[plan.Source]; ok {
+		if plan.Checksum != "" && running.checksum == plan.Checksum {
+			return
+		}
+		running.cancel()
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.running[plan.Source] = scheduledRollout{cancel: cancel, checksum: plan.Checksum}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.finish(ctx, plan.Source)
+		r.run(ctx, plan, interval, waitReady)
+	}()
+}
+
+// finish forgets the rollout of a source unless it was superseded.
+func (r *RolloutScheduler) finish(ctx context.Context, source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ctx.Err() == nil {
+		r.running[source].cancel()
+		delete(r.running, source)
+	}
+}
+
+// inProgress returns true while the rollout of a source is running.
+func (r *RolloutScheduler) inProgress(source string) bool {
+	r.init()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.running[source]
+	return ok
+}
+
+// run restarts the namespaces of the plan one after the other.
+func (r *RolloutScheduler) run(ctx context.Context, plan *rolloutPlan, interval time.Duration, waitReady bool) {
+	logger := log.FromContext(ctx).WithValues("source", plan.Source)
+	restartedBefore := false
+	for _, step := range plan.Steps {
+		// Only namespaces that had workloads to restart are spaced
+		if restartedBefore && interval > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+		if !r.acquire(ctx) {
+			return
+		}
+		restarted := plan.restart(ctx, step)
+		if waitReady && len(restarted) > 0 {
+			if err := r.waitReady(ctx, restarted); err != nil && ctx.Err() == nil {
+				logger.Info("Restarted workloads did not become ready in time, continuing", "namespace", step.Namespace)
+			}
+		}
+		r.release()
+		restartedBefore = len(restarted) > 0
+	}
+}
+
+// acquire takes a concurrency slot and returns false if the context is done.
+func (r *RolloutScheduler) acquire(ctx context.Context) bool {
+	if r.slots == nil {
+		return ctx.Err() == nil
+	}
+	select {
+	case r.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release returns a concurrency slot.
+func (r *RolloutScheduler) release() {
+	if r.slots != nil {
+		<-r.slots
+	}
+}
+
+// waitReady waits until all workloads finished their rollout or the timeout
+// expired.
+func (r *RolloutScheduler) waitReady(ctx context.Context, workloads []Workload) error {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	return wait.PollUntilContextCancel(ctx, r.pollInterval, true, func(ctx context.Context) (bool, error) {
+		for _, workload := range workloads {
+			obj, ok := workload.Object.DeepCopyObject().(client.Object)
+			if !ok {
+				continue
+			}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+				return false, client.IgnoreNotFound(err)
+			}
+			if !IsWorkloadReady(obj) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// IsWorkloadReady returns true if the latest pod template of a Deployment,
+// StatefulSet or DaemonSet is rolled out and available. Other kinds have no
+// rollout to wait for.
+func IsWorkloadReady(obj client.Object) bool {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		replicas := replicasOf(o.Spec.Replicas)
+		return o.Status.ObservedGeneration >= o.Generation &&
+			o.Status.UpdatedReplicas == replicas &&
+			o.Status.Replicas == replicas &&
+			o.Status.AvailableReplicas == replicas
+	case *appsv1.StatefulSet:
+		replicas := replicasOf(o.Spec.Replicas)
+		return o.Status.ObservedGeneration >= o.Generation &&
+			o.Status.UpdatedReplicas == replicas &&
+			o.Status.ReadyReplicas == replicas
+	case *appsv1.DaemonSet:
+		return o.Status.ObservedGeneration >= o.Generation &&
+			o.Status.UpdatedNumberScheduled == o.Status.DesiredNumberScheduled &&
+			o.Status.NumberAvailable == o.Status.DesiredNumberScheduled
+	}
+	return true
+}
+
+// replicasOf returns the desired replicas, which default to one.
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RolloutSelectorKey limits the workloads restarted by rollout-on-update to
+// the ones whose labels match, optionally per target namespace.
+const RolloutSelectorKey = "replizieren.dev/rollout-selector"
+
+// rolloutSelectorNone is the selector value that restarts no workloads.
+const rolloutSelectorNone = "none"
+
+// RolloutSelector selects the workloads restarted when a source changes.
+// The zero value selects all workloads.
+type RolloutSelector struct {
+	// Default selects the workloads in namespaces without an override, nil
+	// selects all.
+	Default labels.Selector
+	// Overrides select the workloads of some namespaces, the first override
+	// that lists a namespace wins.
+	Overrides []RolloutSelectorOverride
+}
+
+// RolloutSelectorOverride selects the workloads of namespaces listed by name or
+// glob pattern.
+type RolloutSelectorOverride struct {
+	Namespaces []string
+	Selector   labels.Selector
+}
+
+// ParseRolloutSelector parses the rollout-selector annotation, e.g.
+// "tier=frontend; prod-*: tier in (frontend,api); dev,qa: none". Entries are
+// separated by semicolons. An entry without namespaces is the default, entries
+// prefixed by namespaces and a colon override it. "none" restarts no workloads.
+func ParseRolloutSelector(value string) (RolloutSelector, error) {
+	var result RolloutSelector
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		// Label selectors never contain a colon
+		namespaces, expression, override := strings.Cut(entry, ":")
+		if !override {
+			expression = entry
+		}
+		selector, err := parseWorkloadSelector(expression)
+		if err != nil {
+			return RolloutSelector{}, err
+		}
+		if !override {
+			if result.Default != nil {
+				return RolloutSelector{}, fmt.Errorf("duplicate default selector %q", entry)
+			}
+			result.Default = selector
+			continue
+		}
+		patterns := SplitList(namespaces)
+		if len(patterns) == 0 {
+			return RolloutSelector{}, fmt.Errorf("invalid entry %q: expected namespace,...: selector", entry)
+		}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return RolloutSelector{}, fmt.Errorf("invalid namespace pattern %q", pattern)
+			}
+		}
+		result.Overrides = append(result.Overrides, RolloutSelectorOverride{Namespaces: patterns, Selector: selector})
+	}
+	return result, nil
+}
+
+// parseWorkloadSelector parses a label selector or "none".
+func parseWorkloadSelector(expression string) (labels.Selector, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == rolloutSelectorNone {
+		return labels.Nothing(), nil
+	}
+	selector, err := labels.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %w", expression, err)
+	}
+	return selector, nil
+}
+
+// For returns the selector of the workloads in the namespace.
+func (s RolloutSelector) For(namespace string) labels.Selector {
+	for _, override := range s.Overrides {
+		if matchesNamespacePattern(override.Namespaces, namespace) {
+			return override.Selector
+		}
+	}
+	if s.Default == nil {
+		return labels.Everything()
+	}
+	return s.Default
+}
+
+// matchesNamespacePattern returns true if the namespace is listed by name or
+// matches one of the glob patterns.
+func matchesNamespacePattern(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if pattern == namespace {
+			return true
+		}
+		if !IsNamespacePattern(pattern) {
+			continue
+		}
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestParseRolloutSelector(t *testing.T) {
+	selector, err := ParseRolloutSelector("tier=frontend; prod-*: tier in (frontend, api); dev, qa: none")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		namespace string
+		labels    labels.Set
+		want      bool
+	}{
+		{namespace: "staging", labels: labels.Set{"tier": "frontend"}, want: true},
+		{namespace: "staging", labels: labels.Set{"tier": "api"}},
+		{namespace: "prod-eu", labels: labels.Set{"tier": "api"}, want: true},
+		{namespace: "prod-eu", labels: labels.Set{"tier": "db"}},
+		{namespace: "dev", labels: labels.Set{"tier": "frontend"}},
+		{namespace: "qa"},
+	}
+	for _, tt := range tests {
+		if got := selector.For(tt.namespace).Matches(tt.labels); got != tt.want {
+			t.Errorf("For(%q).Matches(%v) = %v, want %v", tt.namespace, tt.labels, got, tt.want)
+		}
+	}
+
+	if !(RolloutSelector{}).For("any").Matches(labels.Set{"tier": "db"}) {
+		t.Error("expected the zero value to select all workloads")
+	}
+
+	for _, value := range []string{"tier in (frontend", "a=b; c=d", ": tier=frontend", "prod-[: tier=frontend"} {
+		if _, err := ParseRolloutSelector(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestSyncSource_RolloutSelector(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey:       "dev, prod",
+				RolloutOnUpdateKey: "true",
+				RolloutSelectorKey: "tier=frontend; dev: none",
+			},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	deployment := func(namespace, name, tier string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"tier": tier}},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+		}
+	}
+	f := newSyncFixture(t, source,
+		deployment("prod", "web", "frontend"),
+		deployment("prod", "worker", "backend"),
+		deployment("dev", "web", "frontend"),
+	)
+
+	f.sync(source)
+	if f.checksum(source, "prod", "web") == "" {
+		t.Error("expected the selected Deployment to be restarted")
+	}
+	if f.checksum(source, "prod", "worker") != "" {
+		t.Error("expected Deployment that does not match the selector to be left alone")
+	}
+	if f.checksum(source, "dev", "web") != "" {
+		t.Error("expected no Deployment to be restarted in a namespace overridden with none")
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestIsWorkloadReady(t *testing.T) {
+	two := int32(2)
+	tests := []struct {
+		name  string
+		obj   client.Object
+		ready bool
+	}{
+		{
+			name: "deployment rolled out",
+			obj: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: &two},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			},
+			ready: true,
+		},
+		{
+			name: "deployment generation not observed",
+			obj: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       appsv1.DeploymentSpec{Replicas: &two},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			},
+		},
+		{
+			name: "deployment with old pods",
+			obj: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: &two},
+				Status: appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2},
+			},
+		},
+		{
+			name: "deployment defaults to one replica",
+			obj: &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+			ready: true,
+		},
+		{
+			name: "statefulset not ready",
+			obj: &appsv1.StatefulSet{
+				Spec:   appsv1.StatefulSetSpec{Replicas: &two},
+				Status: appsv1.StatefulSetStatus{UpdatedReplicas: 2, ReadyReplicas: 1},
+			},
+		},
+		{
+			name: "daemonset rolled out",
+			obj: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+			},
+			ready: true,
+		},
+		{
+			name: "daemonset not updated",
+			obj: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberAvailable: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsWorkloadReady(tt.obj); got != tt.ready {
+				t.Errorf("IsWorkloadReady() = %v, want %v", got, tt.ready)
+			}
+		})
+	}
+}
+
+func TestRolloutScheduler_RolloutOptions(t *testing.T) {
+	r := &RolloutScheduler{Interval: time.Minute, WaitReady: true}
+	source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+
+	if interval, waitReady := r.rolloutOptions(source); interval != time.Minute || !waitReady {
+		t.Errorf("expected defaults, got %v, %v", interval, waitReady)
+	}
+
+	source.Annotations[RolloutIntervalKey] = "5s"
+	source.Annotations[RolloutWaitReadyKey] = "false"
+	if interval, waitReady := r.rolloutOptions(source); interval != 5*time.Second || waitReady {
+		t.Errorf("expected overrides, got %v, %v", interval, waitReady)
+	}
+
+	source.Annotations[RolloutIntervalKey] = "soon"
+	if interval, _ := r.rolloutOptions(source); interval != time.Minute {
+		t.Errorf("expected invalid interval to be ignored, got %v", interval)
+	}
+}
+
+func TestRolloutScheduler_SpacesNamespaces(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+
+	newDeployment := func(namespace string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).
+		WithObjects(newDeployment("first"), newDeployment("second")).Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The first deployment never becomes ready, so the scheduler waits for the
+	// timeout and the interval before restarting the second namespace
+	r := &RolloutScheduler{
+		Client:         c,
+		MaxConcurrency: 1,
+		Interval:       100 * time.Millisecond,
+		WaitReady:      true,
+		Timeout:        200 * time.Millisecond,
+		pollInterval:   10 * time.Millisecond,
+	}
+	go func() { _ = r.Start(ctx) }()
+
+	checksumOf := func(w *Workload) (string, bool) { return "v1", IsPodSpecUsingSecret(&w.Template.Spec, "creds") }
+	source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default"}}
+	start := time.Now()
+	r.Schedule(source, &rolloutPlan{
+		Source:        "Secret/default/creds",
+		Client:        c,
+		AnnotationKey: "replizieren.dev/checksum-secret-creds",
+		Steps: []rolloutStep{
+			{Namespace: "first", ChecksumOf: checksumOf},
+			{Namespace: "second", ChecksumOf: checksumOf},
+		},
+	})
+
+	restarted := func(namespace string) bool {
+		var deploy appsv1.Deployment
+		if err := c.Get(ctx, types.NamespacedName{Name: "app", Namespace: namespace}, &deploy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return deploy.Spec.Template.Annotations["replizieren.dev/checksum-secret-creds"] == "v1"
+	}
+	waitFor := func(namespace string) time.Duration {
+		deadline := time.Now().Add(5 * time.Second)
+		for !restarted(namespace) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected deployment in %s to be restarted", namespace)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return time.Since(start)
+	}
+
+	waitFor("first")
+	if elapsed := waitFor("second"); elapsed < 300*time.Millisecond {
+		t.Errorf("expected second namespace to be restarted after timeout and interval, got %v", elapsed)
+	}
+}
+
+func TestRolloutScheduler_KeepsIdenticalPlan(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+
+	newDeployment := func(namespace string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).
+		WithObjects(newDeployment("first"), newDeployment("second")).Build()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The first deployment never becomes ready, so the rollout waits there
+	// until it is superseded
+	r := &RolloutScheduler{
+		Client:       c,
+		WaitReady:    true,
+		Timeout:      time.Minute,
+		pollInterval: 10 * time.Millisecond,
+	}
+	go func() { _ = r.Start(ctx) }()
+
+	checksumOf := func(w *Workload) (string, bool) { return "v1", IsPodSpecUsingSecret(&w.Template.Spec, "creds") }
+	source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default"}}
+	plan := func(checksum string) *rolloutPlan {
+		return &rolloutPlan{
+			Source:        "Secret/default/creds",
+			Client:        c,
+			AnnotationKey: "replizieren.dev/checksum-secret-creds",
+			Steps: []rolloutStep{
+				{Namespace: "first", ChecksumOf: checksumOf},
+				{Namespace: "second", ChecksumOf: checksumOf},
+			},
+			Checksum: checksum,
+		}
+	}
+	restarted := func(namespace string) bool {
+		var deploy appsv1.Deployment
+		if err := c.Get(ctx, types.NamespacedName{Name: "app", Namespace: namespace}, &deploy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return deploy.Spec.Template.Annotations["replizieren.dev/checksum-secret-creds"] == "v1"
+	}
+	eventually := func(namespace string) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for !restarted(namespace) {
+			if time.Now().After(deadline) {
+				return false
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		return true
+	}
+
+	r.Schedule(source, plan("a"))
+	if !eventually("first") {
+		t.Fatal("expected deployment in first to be restarted")
+	}
+
+	// An identical plan keeps waiting for the first namespace
+	r.Schedule(source, plan("a"))
+	time.Sleep(100 * time.Millisecond)
+	if restarted("second") {
+		t.Fatal("expected an identical plan to not start the rollout over")
+	}
+
+	// A different plan supersedes the rollout, the first namespace is already
+	// restarted, so it moves on to the second right away
+	r.Schedule(source, plan("b"))
+	if !eventually("second") {
+		t.Error("expected a different plan to supersede the rollout")
+	}
+}
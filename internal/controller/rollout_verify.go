@@ -0,0 +1,365 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// Event and condition reasons for rollouts
+const (
+	reasonRolloutCompleted         = "RolloutCompleted"
+	reasonRolloutFailed            = "RolloutFailed"
+	reasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+)
+
+// rolloutVerifyInterval is the interval in which a source is synced again
+// while workloads restarted for its content are rolling out.
+const rolloutVerifyInterval = 30 * time.Second
+
+// rolloutState is the state of the rollout of a restarted workload.
+type rolloutState int
+
+const (
+	// rolloutPending means the workload was not restarted for the content yet.
+	rolloutPending rolloutState = iota
+	rolloutProgressing
+	rolloutCompleted
+	// rolloutDeadlineExceeded means a workload exceeded its progress deadline.
+	rolloutDeadlineExceeded
+)
+
+// rolloutStateOf returns the state of the rollout of a workload restarted with
+// the checksum.
+func rolloutStateOf(
+	ctx context.Context,
+	c client.Client,
+	workload *Workload,
+	annotationKey, checksum string,
+	now time.Time,
+) (rolloutState, error) {
+	obj := workload.Object
+	if workload.Template.Annotations[annotationKey] != checksum {
+		return rolloutPending, nil
+	}
+	if isStatusObserved(obj) {
+		failed, err := isWorkloadFailed(ctx, c, obj, annotationKey, checksum, now)
+		if err != nil {
+			return rolloutPending, err
+		}
+		if failed {
+			return rolloutDeadlineExceeded, nil
+		}
+	}
+	if IsWorkloadReady(obj) {
+		return rolloutCompleted, nil
+	}
+	return rolloutProgressing, nil
+}
+
+// hasRollout returns true for Deployments, StatefulSets and DaemonSets. Other
+// kinds have no rollout to verify.
+func hasRollout(obj client.Object) bool {
+	switch obj.(type) {
+	case *appsv1.Deployment, *appsv1.StatefulSet, *appsv1.DaemonSet:
+		return true
+	}
+	return false
+}
+
+// isStatusObserved returns true if the status of a workload reflects its
+// latest pod template. Until then, the status belongs to the previous rollout.
+func isStatusObserved(obj client.Object) bool {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return o.Status.ObservedGeneration >= o.Generation
+	case *appsv1.StatefulSet:
+		return o.Status.ObservedGeneration >= o.Generation
+	case *appsv1.DaemonSet:
+		return o.Status.ObservedGeneration >= o.Generation
+	}
+	return true
+}
+
+// RolloutTracker remembers the workloads restarted for the sources, so their
+// rollouts are verified and their outcome is reported once. The state is kept
+// in memory, after a restart of the operator earlier restarts are no longer
+// verified.
+type RolloutTracker struct {
+	mu       sync.Mutex
+	rollouts map[string]map[workloadRef]rolloutMark
+}
+
+// workloadRef identifies a restarted workload.
+type workloadRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// rolloutMark records when a workload was restarted with a checksum and
+// whether the failure of its rollout was reported.
+type rolloutMark struct {
+	checksum string
+	since    time.Time
+	failed   bool
+}
+
+// restarted records the rollouts of the restarted Deployments, StatefulSets
+// and DaemonSets of the source. Without a tracker rollouts are not verified.
+func (t *RolloutTracker) restarted(source, annotationKey string, workloads []Workload, now time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, workload := range workloads {
+		if !hasRollout(workload.Object) {
+			continue
+		}
+		if t.rollouts == nil {
+			t.rollouts = map[string]map[workloadRef]rolloutMark{}
+		}
+		if t.rollouts[source] == nil {
+			t.rollouts[source] = map[workloadRef]rolloutMark{}
+		}
+		ref := workloadRef{Kind: workload.Kind, Namespace: workload.Object.GetNamespace(), Name: workload.Object.GetName()}
+		t.rollouts[source][ref] = rolloutMark{checksum: workload.Template.Annotations[annotationKey], since: now}
+	}
+}
+
+// tracked returns the workloads restarted for the source whose rollouts are
+// verified.
+func (t *RolloutTracker) tracked(source string) []workloadRef {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	refs := slices.Collect(maps.Keys(t.rollouts[source]))
+	slices.SortFunc(refs, func(a, b workloadRef) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Name, b.Name))
+	})
+	return refs
+}
+
+// observe records the state of the rollout of a restarted workload. It
+// returns true and the time of the restart when the rollout completed or
+// exceeded its progress deadline, once per rollout. Completed rollouts and
+// rollouts superseded by a newer change are no longer tracked.
+func (t *RolloutTracker) observe(source string, ref workloadRef, checksum string, state rolloutState) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	mark, ok := t.rollouts[source][ref]
+	if !ok {
+		return time.Time{}, false
+	}
+	if mark.checksum != checksum {
+		t.remove(source, ref)
+		return time.Time{}, false
+	}
+	switch state {
+	case rolloutCompleted:
+		t.remove(source, ref)
+		return mark.since, !mark.failed
+	case rolloutDeadlineExceeded:
+		if !mark.failed {
+			mark.failed = true
+			t.rollouts[source][ref] = mark
+			return mark.since, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// untrack stops tracking the rollout of a workload that was deleted or is no
+// longer restarted for the source.
+func (t *RolloutTracker) untrack(source string, ref workloadRef) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.remove(source, ref)
+}
+
+// forget stops tracking the rollouts of a deleted source.
+func (t *RolloutTracker) forget(kind replicationv1alpha1.SourceKind, key types.NamespacedName) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.rollouts, fmt.Sprintf("%s/%s/%s", kind, key.Namespace, key.Name))
+}
+
+// remove stops tracking the rollout of a workload. The caller holds the lock.
+func (t *RolloutTracker) remove(source string, ref workloadRef) {
+	delete(t.rollouts[source], ref)
+	if len(t.rollouts[source]) == 0 {
+		delete(t.rollouts, source)
+	}
+}
+
+// rolloutReport summarizes the rollouts of the workloads restarted for the
+// current content of a source.
+type rolloutReport struct {
+	// Failed lists the workloads that exceeded their progress deadline.
+	Failed []string
+	// Progressing is true while restarted workloads are rolling out or
+	// waiting to be restarted.
+	Progressing bool
+}
+
+// verifyRollouts checks the rollouts of the Deployments, StatefulSets and
+// DaemonSets restarted for the current content of the source in the
+// namespaces of the steps. Rollouts that completed or exceeded their progress
+// deadline are recorded as events on the source and the workload and in
+// metrics.
+func (s *sourceSyncer) verifyRollouts(ctx context.Context, source client.Object, steps []rolloutStep) (rolloutReport, error) {
+	var report rolloutReport
+	if s.DryRun || IsDryRun(source) {
+		return report, nil
+	}
+	sourceRef := fmt.Sprintf("%s/%s/%s", SourceKindOf(source), source.GetNamespace(), source.GetName())
+	// Restarts scheduled in the background may not have happened yet
+	report.Progressing = s.Rollouts != nil && s.Rollouts.inProgress(sourceRef)
+	annotationKey := checksumAnnotationKey(source)
+	now := time.Now()
+
+	checksumOf := make(map[string]RestartWorkloadsFunc, len(steps))
+	for _, step := range steps {
+		checksumOf[step.Namespace] = step.ChecksumOf
+	}
+	for _, ref := range s.RolloutTracker.tracked(sourceRef) {
+		stepChecksumOf, ok := checksumOf[ref.Namespace]
+		if !ok {
+			continue
+		}
+		workload, err := getWorkload(ctx, s.Client, ref)
+		if errors.IsNotFound(err) {
+			s.RolloutTracker.untrack(sourceRef, ref)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		checksum, ok := stepChecksumOf(workload)
+		if !ok {
+			s.RolloutTracker.untrack(sourceRef, ref)
+			continue
+		}
+		name := fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+		state, err := rolloutStateOf(ctx, s.Client, workload, annotationKey, checksum, now)
+		if err != nil {
+			return report, err
+		}
+		switch state {
+		case rolloutPending:
+			// The workload waits for the restart of a newer change
+			s.RolloutTracker.untrack(sourceRef, ref)
+			continue
+		case rolloutProgressing:
+			report.Progressing = true
+		case rolloutDeadlineExceeded:
+			report.Failed = append(report.Failed, name)
+		}
+
+		if since, finished := s.RolloutTracker.observe(sourceRef, ref, checksum, state); finished {
+			s.recordRollout(ctx, source, workload, name, state, now.Sub(since))
+		}
+	}
+	slices.Sort(report.Failed)
+	return report, nil
+}
+
+// getWorkload returns a restarted Deployment, StatefulSet or DaemonSet.
+func getWorkload(ctx context.Context, c client.Client, ref workloadRef) (*Workload, error) {
+	workload := &Workload{Kind: ref.Kind}
+	switch ref.Kind {
+	case "Deployment":
+		d := &appsv1.Deployment{}
+		workload.Object, workload.Template = d, &d.Spec.Template
+	case "StatefulSet":
+		s := &appsv1.StatefulSet{}
+		workload.Object, workload.Template = s, &s.Spec.Template
+	case "DaemonSet":
+		d := &appsv1.DaemonSet{}
+		workload.Object, workload.Template = d, &d.Spec.Template
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %s", ref.Kind)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, workload.Object); err != nil {
+		return nil, err
+	}
+	return workload, nil
+}
+
+// recordRollout reports the outcome of a rollout as events on the source and
+// the workload and in metrics.
+func (s *sourceSyncer) recordRollout(
+	ctx context.Context,
+	source client.Object,
+	workload *Workload,
+	name string,
+	state rolloutState,
+	duration time.Duration,
+) {
+	logger := log.FromContext(ctx)
+	sourceName := fmt.Sprintf("%s %s/%s", SourceKindOf(source), source.GetNamespace(), source.GetName())
+	namespace := workload.Object.GetNamespace()
+
+	if state == rolloutDeadlineExceeded {
+		logger.Info("Rollout of restarted workload failed", "workload", name)
+		rolloutsTotal.WithLabelValues(workload.Kind, namespace, rolloutFailed).Inc()
+		if s.Recorder != nil {
+			s.Recorder.Eventf(source, workload.Object, corev1.EventTypeWarning, reasonRolloutFailed, "Rollout",
+				"%s exceeded its progress deadline after the change", name)
+			s.Recorder.Eventf(workload.Object, source, corev1.EventTypeWarning, reasonRolloutFailed, "Rollout",
+				"Rollout for a change of %s exceeded the progress deadline", sourceName)
+		}
+		return
+	}
+
+	logger.Info("Rollout of restarted workload completed", "workload", name, "duration", duration)
+	rolloutsTotal.WithLabelValues(workload.Kind, namespace, rolloutComplete).Inc()
+	rolloutDurationSeconds.WithLabelValues(workload.Kind).Observe(duration.Seconds())
+	if s.Recorder != nil {
+		s.Recorder.Eventf(source, workload.Object, corev1.EventTypeNormal, reasonRolloutCompleted, "Rollout",
+			"%s rolled out the change", name)
+		s.Recorder.Eventf(workload.Object, source, corev1.EventTypeNormal, reasonRolloutCompleted, "Rollout",
+			"Rolled out a change of %s", sourceName)
+	}
+}
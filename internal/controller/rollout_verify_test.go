@@ -0,0 +1,234 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestRolloutTracker_Observe(t *testing.T) {
+	tracker := &RolloutTracker{}
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ref := workloadRef{Kind: "Deployment", Namespace: "apps", Name: "app"}
+	restart := func(checksum string) {
+		deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps"}}
+		deploy.Spec.Template.Annotations = map[string]string{"checksum": checksum}
+		tracker.restarted("source", "checksum", []Workload{{Kind: "Deployment", Object: deploy, Template: &deploy.Spec.Template}}, start)
+	}
+
+	if _, finished := tracker.observe("source", ref, "v1", rolloutCompleted); finished {
+		t.Error("expected rollouts of workloads that were not restarted not to be reported")
+	}
+	restart("v1")
+	if refs := tracker.tracked("source"); len(refs) != 1 || refs[0] != ref {
+		t.Errorf("expected the restarted workload to be tracked, got %v", refs)
+	}
+	tracker.observe("source", ref, "v1", rolloutProgressing)
+	if since, finished := tracker.observe("source", ref, "v1", rolloutCompleted); !finished || !since.Equal(start) {
+		t.Errorf("expected completed rollout since %v, got %v (%v)", start, since, finished)
+	}
+	if refs := tracker.tracked("source"); len(refs) != 0 {
+		t.Errorf("expected completed rollouts to no longer be tracked, got %v", refs)
+	}
+
+	// Failures are reported once and tracked until the rollout completes
+	restart("v2")
+	if _, finished := tracker.observe("source", ref, "v2", rolloutDeadlineExceeded); !finished {
+		t.Error("expected the failed rollout to be reported")
+	}
+	if _, finished := tracker.observe("source", ref, "v2", rolloutDeadlineExceeded); finished {
+		t.Error("expected the failure to be reported once")
+	}
+	if _, finished := tracker.observe("source", ref, "v2", rolloutCompleted); finished {
+		t.Error("expected a rollout reported as failed not to be reported as completed")
+	}
+
+	restart("v3")
+	if _, finished := tracker.observe("source", ref, "v4", rolloutDeadlineExceeded); finished {
+		t.Error("expected a newer change to supersede the rollout")
+	}
+	if refs := tracker.tracked("source"); len(refs) != 0 {
+		t.Errorf("expected superseded rollouts to no longer be tracked, got %v", refs)
+	}
+
+	var nilTracker *RolloutTracker
+	if _, finished := nilTracker.observe("source", ref, "v1", rolloutCompleted); finished {
+		t.Error("expected a nil tracker never to report")
+	}
+}
+
+func TestSyncSource_VerifiesRollouts(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Data:       map[string][]byte{"password": []byte("v1")},
+	}
+	replication := &replicationv1alpha1.Replication{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Spec: replicationv1alpha1.ReplicationSpec{
+			Source:  replicationv1alpha1.SourceReference{Kind: replicationv1alpha1.SourceKindSecret, Name: "creds"},
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"verify-ns"}},
+			Options: replicationv1alpha1.ReplicationOptions{RolloutOnUpdate: pointerTo(true)},
+		},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "verify-ns"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	// A Deployment that already runs the content is not verified, even if its
+	// rollout failed
+	podSpec := podSpecUsingSecret("creds")
+	checksum, _ := consumedKeysHash(source, &podSpec)
+	current := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "current", Namespace: "verify-ns"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{checksumAnnotationKey(source): checksum}},
+			Spec:       podSpec,
+		}},
+		Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{{
+			Type:   appsv1.DeploymentProgressing,
+			Status: corev1.ConditionFalse,
+			Reason: "ProgressDeadlineExceeded",
+		}}},
+	}
+	f := newSyncFixture(t, source, replication, deploy, current)
+	f.syncer.RolloutTracker = &RolloutTracker{}
+
+	setStatus := func(status appsv1.DeploymentStatus) {
+		t.Helper()
+		f.get(deploy)
+		status.ObservedGeneration = deploy.Generation
+		deploy.Status = status
+		if err := f.client.Status().Update(f.ctx, deploy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	sync := func() time.Duration {
+		t.Helper()
+		_, requeueAfter := f.sync(source)
+		f.get(replication)
+		return requeueAfter
+	}
+	failures := func() float64 {
+		t.Helper()
+		var metric dto.Metric
+		if err := rolloutsTotal.WithLabelValues("Deployment", "verify-ns", rolloutFailed).Write(&metric); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return metric.GetCounter().GetValue()
+	}
+
+	// The restarted Deployment is rolling out
+	if requeueAfter := sync(); requeueAfter != rolloutVerifyInterval {
+		t.Errorf("expected requeue after %v while rolling out, got %v", rolloutVerifyInterval, requeueAfter)
+	}
+	f.expectEvents("Normal Replicated", "Normal RestartedBy")
+
+	failedBefore := failures()
+	setStatus(appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{{
+		Type:   appsv1.DeploymentProgressing,
+		Status: corev1.ConditionFalse,
+		Reason: "ProgressDeadlineExceeded",
+	}}})
+	if requeueAfter := sync(); requeueAfter != 0 {
+		t.Errorf("expected no requeue after the rollout failed, got %v", requeueAfter)
+	}
+	condition := meta.FindStatusCondition(replication.Status.Conditions, replicationv1alpha1.ConditionRolloutFailed)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Message != "Deployment verify-ns/app exceeded its progress deadline" {
+		t.Errorf("expected RolloutFailed condition naming the restarted Deployment, got %+v", condition)
+	}
+	if failures() != failedBefore+1 {
+		t.Error("expected the failed rollout to be counted")
+	}
+	for _, target := range []string{"exceeded its progress deadline after the change", "Rollout for a change of Secret source-ns/creds"} {
+		select {
+		case event := <-f.recorder.Events:
+			if !strings.HasPrefix(event, "Warning RolloutFailed") || !strings.Contains(event, target) {
+				t.Errorf("expected RolloutFailed event containing %q, got %q", target, event)
+			}
+		default:
+			t.Errorf("expected RolloutFailed event containing %q", target)
+		}
+	}
+
+	// Reported once, the condition remains until the Deployment recovers
+	sync()
+	if len(f.recorder.Events) != 0 || failures() != failedBefore+1 {
+		t.Error("expected the failure to be reported once")
+	}
+	setStatus(appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1})
+	sync()
+	if meta.FindStatusCondition(replication.Status.Conditions, replicationv1alpha1.ConditionRolloutFailed) != nil {
+		t.Error("expected RolloutFailed condition to be removed once the Deployment is available")
+	}
+}
+
+func TestSyncSource_PrunesRolloutTracker(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "creds",
+			Namespace:   "apps",
+			Annotations: map[string]string{RolloutOnUpdateKey: "true"},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	deployment := func(name string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+		}
+	}
+	deleted, optedOut := deployment("deleted"), deployment("opted-out")
+	f := newSyncFixture(t, source, deleted, optedOut)
+	tracker := &RolloutTracker{}
+	f.syncer.RolloutTracker = tracker
+	sourceRef := "Secret/apps/creds"
+
+	f.sync(source)
+	if refs := tracker.tracked(sourceRef); len(refs) != 2 {
+		t.Fatalf("expected both restarted Deployments to be tracked, got %v", refs)
+	}
+
+	// Workloads that are gone or opted out are no longer tracked
+	if err := f.client.Delete(f.ctx, deleted); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.get(optedOut)
+	optedOut.Annotations = map[string]string{ReloadKey: ReloadDisabled}
+	f.update(optedOut)
+	f.sync(source)
+	if refs := tracker.tracked(sourceRef); len(refs) != 0 {
+		t.Errorf("expected no tracked rollouts, got %v", refs)
+	}
+
+	// Deleted sources are forgotten
+	tracker.restarted(sourceRef, checksumAnnotationKey(source), []Workload{{Kind: "Deployment", Object: deleted, Template: &deleted.Spec.Template}}, time.Now())
+	tracker.forget(replicationv1alpha1.SourceKindSecret, client.ObjectKeyFromObject(source))
+	if refs := tracker.tracked(sourceRef); len(refs) != 0 {
+		t.Errorf("expected the rollouts of a deleted source to be forgotten, got %v", refs)
+	}
+}
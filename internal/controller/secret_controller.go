@@ -19,106 +19,117 @@ package controller
 import (
 	"context"
 
-	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
 )
 
 // SecretReconciler reconciles a Secret object
 type SecretReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	SyncOptions
 }
 
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=secrets/finalizers,verbs=update
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
-// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=replication.replizieren.dev,resources=replicationpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=replication.replizieren.dev,resources=replications,verbs=get;list;watch
+// +kubebuilder:rbac:groups=replication.replizieren.dev,resources=replications/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 // Reconcile handles Secret replication and deployment rollout triggers.
 func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	logger := log.FromContext(ctx)
-
 	var secret corev1.Secret
 	if err := r.Get(ctx, req.NamespacedName, &secret); err != nil {
 		if errors.IsNotFound(err) {
-			return ctrl.Result{}, nil
+			r.Seeds.forget(replicationv1alpha1.SourceKindSecret, req.NamespacedName)
+			r.RolloutTracker.forget(replicationv1alpha1.SourceKindSecret, req.NamespacedName)
+			r.Waves.forget(replicationv1alpha1.SourceKindSecret, req.NamespacedName)
+			r.Resyncer.forget(replicationv1alpha1.SourceKindSecret, req.NamespacedName)
+			return ctrl.Result{}, markSourceNotFound(ctx, r.syncer().writer(nil), replicationv1alpha1.SourceKindSecret, req.NamespacedName)
 		}
 		return ctrl.Result{}, err
 	}
 
-	config := ParseReplicationConfig(secret.Annotations, secret.Namespace)
-
-	if config.SkipReplication && !config.RolloutOnUpdate {
-		logger.Info("Replication not set, skipping")
-		return ctrl.Result{}, nil
-	}
-
-	targetNamespaces := config.TargetNamespaces
-	if config.ReplicateAll {
-		var err error
-		targetNamespaces, err = GetAllNamespaces(ctx, r.Client, secret.Namespace)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-	}
+	return r.syncer().sync(ctx, &secret)
+}
 
-	for _, ns := range targetNamespaces {
-		if !config.SkipReplication {
-			if err := r.replicateSecret(ctx, &secret, ns); err != nil {
-				logger.Error(err, "Failed to replicate secret", "namespace", ns)
-				continue
-			}
-		}
-		if config.RolloutOnUpdate {
-			if err := RestartDeployments(ctx, r.Client, ns, "secret.restartedAt", func(d *appsv1.Deployment) bool {
-				return IsDeploymentUsingSecret(d, secret.Name)
-			}); err != nil {
-				logger.Error(err, "Failed to restart deployments", "namespace", ns)
-			}
-		}
-	}
+func (r *SecretReconciler) syncer() *sourceSyncer {
+	return &sourceSyncer{Client: r.Client, SyncOptions: r.SyncOptions}
+}
 
-	// Also trigger rollout in source namespace if enabled
-	if config.RolloutOnUpdate {
-		if err := RestartDeployments(ctx, r.Client, secret.Namespace, "secret.restartedAt", func(d *appsv1.Deployment) bool {
-			return IsDeploymentUsingSecret(d, secret.Name)
-		}); err != nil {
-			logger.Error(err, "Failed to restart deployments in source namespace", "namespace", secret.Namespace)
-		}
+// secretsForPolicy enqueues the secrets selected by a ReplicationPolicy.
+func (r *SecretReconciler) secretsForPolicy(ctx context.Context, obj client.Object) []reconcile.Request {
+	policy, ok := obj.(*replicationv1alpha1.ReplicationPolicy)
+	if !ok {
+		return nil
 	}
-
-	return ctrl.Result{}, nil
+	return requestsForPolicy(ctx, r.Client, policy, replicationv1alpha1.SourceKindSecret)
 }
 
-func (r *SecretReconciler) replicateSecret(ctx context.Context, original *corev1.Secret, namespace string) error {
-	clone := original.DeepCopy()
-	clone.Namespace = namespace
-	clone.ResourceVersion = ""
-	clone.UID = ""
-
-	existing := &corev1.Secret{}
-	err := r.Get(ctx, types.NamespacedName{Name: clone.Name, Namespace: namespace}, existing)
-	if err != nil && errors.IsNotFound(err) {
-		return r.Create(ctx, clone)
-	} else if err != nil {
-		return err
-	}
+// secretForReplication enqueues the secret referenced by a Replication.
+func (r *SecretReconciler) secretForReplication(_ context.Context, obj client.Object) []reconcile.Request {
+	return requestForReplication(obj, replicationv1alpha1.SourceKindSecret)
+}
 
-	clone.ResourceVersion = existing.ResourceVersion
-	return r.Update(ctx, clone)
+// secretsForPause enqueues all secrets when the control ConfigMap changes, so
+// the backlog is reconciled when replication resumes.
+func (r *SecretReconciler) secretsForPause(ctx context.Context, _ client.Object) []reconcile.Request {
+	return requestsForSources(ctx, r.Client, &corev1.SecretList{})
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		Named("secret").
-		Complete(r)
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Secret{}, builder.WithPredicates(isSourcePredicate)).
+		Watches(
+			&corev1.Secret{},
+			r.syncer().replicaHandler(),
+			builder.WithPredicates(isReplicaPredicate),
+		).
+		Watches(
+			&corev1.Secret{},
+			r.syncer().revisionHandler(),
+			builder.WithPredicates(isSourcePredicate),
+		).
+		Watches(
+			&batchv1.Job{},
+			r.syncer().updateJobHandler(),
+			builder.WithPredicates(updateJobPredicate(replicationv1alpha1.SourceKindSecret)),
+		).
+		Watches(
+			&replicationv1alpha1.ReplicationPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.secretsForPolicy),
+		).
+		Watches(
+			&replicationv1alpha1.Replication{},
+			handler.EnqueueRequestsFromMapFunc(r.secretForReplication),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			handler.EnqueueRequestsFromMapFunc(r.secretsForPause),
+			builder.WithPredicates(r.Pause.controlConfigMapPredicate()),
+		).
+		Named("secret")
+	if r.Resyncer != nil {
+		bldr = bldr.WatchesRawSource(r.Resyncer.watchSource(replicationv1alpha1.SourceKindSecret))
+	}
+	return bldr.Complete(r)
 }
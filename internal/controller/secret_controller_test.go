@@ -23,9 +23,12 @@ import (
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
 )
 
 var _ = Describe("Secret Replication", func() {
@@ -316,7 +319,7 @@ var _ = Describe("Secret Replication", func() {
 			if d.Spec.Template.Annotations == nil {
 				return ""
 			}
-			return d.Spec.Template.Annotations["secret.restartedAt"]
+			return d.Spec.Template.Annotations[checksumAnnotationKey(secret)]
 		}, timeout, interval).ShouldNot(BeEmpty())
 	})
 
@@ -391,9 +394,159 @@ var _ = Describe("Secret Replication", func() {
 			if d.Spec.Template.Annotations == nil {
 				return ""
 			}
-			return d.Spec.Template.Annotations["secret.restartedAt"]
+			return d.Spec.Template.Annotations[checksumAnnotationKey(secret)]
 		}, timeout, interval).ShouldNot(BeEmpty())
 	})
+
+	// Test 13: ReplicationPolicy selects sources without annotations
+	It("should replicate secrets selected by a ReplicationPolicy", func() {
+		ns1 := createNamespace("s-policy-src")
+		ns2 := createNamespace("s-policy-tgt")
+		excluded := createNamespace("s-policy-excluded")
+
+		policy := &replicationv1alpha1.ReplicationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "s-policy"},
+			Spec: replicationv1alpha1.ReplicationPolicySpec{
+				Sources: replicationv1alpha1.SourceSelector{
+					Namespace: ns1.Name,
+					Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"shared": "true"}},
+				},
+				Targets: replicationv1alpha1.TargetSelector{
+					Namespaces:        []string{ns2.Name, excluded.Name},
+					ExcludeNamespaces: []string{excluded.Name},
+				},
+				Options: replicationv1alpha1.ReplicationOptions{Keys: []string{"username"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "policy-secret",
+				Namespace: ns1.Name,
+				Labels:    map[string]string{"shared": "true"},
+			},
+			StringData: map[string]string{"username": "admin", "password": "secret123"},
+			Type:       corev1.SecretTypeOpaque,
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		Eventually(func() map[string][]byte {
+			var replicated corev1.Secret
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: ns2.Name}, &replicated); err != nil {
+				return nil
+			}
+			return replicated.Data
+		}, timeout, interval).Should(And(
+			HaveKeyWithValue("username", []byte("admin")),
+			Not(HaveKey("password")),
+		))
+
+		Consistently(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: excluded.Name}, &corev1.Secret{})
+		}, 5*time.Second, interval).ShouldNot(Succeed())
+	})
+
+	// Test 14: Replication reports per-target status
+	It("should replicate secrets referenced by a Replication and report status", func() {
+		ns1 := createNamespace("s-repl-src")
+		ns2 := createNamespace("s-repl-tgt")
+		ns3 := createNamespace("s-repl-conflict")
+
+		unmanaged := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "repl-secret", Namespace: ns3.Name},
+			StringData: map[string]string{"key": "local"},
+		}
+		Expect(k8sClient.Create(ctx, unmanaged)).To(Succeed())
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "repl-secret", Namespace: ns1.Name},
+			StringData: map[string]string{"key": "value"},
+			Type:       corev1.SecretTypeOpaque,
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		replication := &replicationv1alpha1.Replication{
+			ObjectMeta: metav1.ObjectMeta{Name: "repl", Namespace: ns1.Name},
+			Spec: replicationv1alpha1.ReplicationSpec{
+				Source: replicationv1alpha1.SourceReference{
+					Kind: replicationv1alpha1.SourceKindSecret,
+					Name: secret.Name,
+				},
+				Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{ns2.Name, ns3.Name}},
+				Options: replicationv1alpha1.ReplicationOptions{ConflictPolicy: replicationv1alpha1.ConflictPolicySkip},
+			},
+		}
+		Expect(k8sClient.Create(ctx, replication)).To(Succeed())
+
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: ns2.Name}, &corev1.Secret{})
+		}, timeout, interval).Should(Succeed())
+
+		Eventually(func() []replicationv1alpha1.TargetState {
+			var current replicationv1alpha1.Replication
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(replication), &current); err != nil {
+				return nil
+			}
+			var states []replicationv1alpha1.TargetState
+			for _, target := range current.Status.Targets {
+				states = append(states, target.State)
+			}
+			return states
+		}, timeout, interval).Should(ConsistOf(
+			replicationv1alpha1.TargetStateSynced,
+			replicationv1alpha1.TargetStateConflict,
+		))
+
+		var current replicationv1alpha1.Replication
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(replication), &current)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(current.Status.Conditions, replicationv1alpha1.ConditionDegraded)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(current.Status.Conditions, replicationv1alpha1.ConditionReady)).To(BeTrue())
+	})
+
+	// Test 15: Drift on replicas is reverted
+	It("should restore modified and deleted replicas", func() {
+		ns1 := createNamespace("s-drift-src")
+		ns2 := createNamespace("s-drift-tgt")
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "drift-secret",
+				Namespace:   ns1.Name,
+				Annotations: map[string]string{ReplicateKey: ns2.Name},
+			},
+			StringData: map[string]string{"key": "value"},
+			Type:       corev1.SecretTypeOpaque,
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+		replicaKey := types.NamespacedName{Name: secret.Name, Namespace: ns2.Name}
+		var replica corev1.Secret
+		Eventually(func() error {
+			return k8sClient.Get(ctx, replicaKey, &replica)
+		}, timeout, interval).Should(Succeed())
+
+		replica.Data["key"] = []byte("edited")
+		Expect(k8sClient.Update(ctx, &replica)).To(Succeed())
+
+		Eventually(func() string {
+			var current corev1.Secret
+			if err := k8sClient.Get(ctx, replicaKey, &current); err != nil {
+				return ""
+			}
+			return string(current.Data["key"])
+		}, timeout, interval).Should(Equal("value"))
+
+		Expect(k8sClient.Delete(ctx, &replica)).To(Succeed())
+
+		Eventually(func() string {
+			var current corev1.Secret
+			if err := k8sClient.Get(ctx, replicaKey, &current); err != nil {
+				return ""
+			}
+			return string(current.UID)
+		}, timeout, interval).ShouldNot(Or(BeEmpty(), Equal(string(replica.UID))))
+	})
 })
 
 // Helper functions
@@ -0,0 +1,578 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// SyncOptions configures how the Secret and ConfigMap reconcilers replicate
+// sources and restart workloads. Optional trackers disable their feature when
+// nil.
+type SyncOptions struct {
+	Recorder events.EventRecorder
+	Pause    *Pause
+	DryRun   bool
+	// WorkloadKinds are custom kinds restarted in addition to the built-in ones.
+	WorkloadKinds []WorkloadKind
+	// Rollouts restarts workloads in the background. Workloads are restarted
+	// immediately if it is nil.
+	Rollouts *RolloutScheduler
+	// Waves tracks the progress of sources propagated in waves. Waves are not
+	// delayed if it is nil.
+	Waves *WaveTracker
+	// Revisions keeps the history of the replicated payload if set.
+	Revisions *RevisionHistory
+	// RolloutTracker verifies the rollouts of restarted workloads and reports
+	// their outcome. Rollouts are not verified if it is nil.
+	RolloutTracker *RolloutTracker
+	// Seeds seeds the checksums of workloads on the first sync of a source.
+	// Workloads without a checksum are always restarted if it is nil.
+	Seeds *ChecksumSeeder
+	// Reloads finds the workloads that opted in to reloads of sources that
+	// are neither replicated nor rolled out. Every such source lists the
+	// workloads of its namespace if it is nil.
+	Reloads *ReloadIndex
+	// Resyncer periodically enqueues every source. Sources are only synced
+	// on events if it is nil.
+	Resyncer *Resyncer
+}
+
+// sourceSyncer implements the replication and rollout logic shared by the
+// Secret and ConfigMap reconcilers.
+type sourceSyncer struct {
+	client.Client
+	SyncOptions
+}
+
+// writer returns the client used for writes caused by the source.
+func (s *sourceSyncer) writer(source client.Object) client.Client {
+	return writerFor(s.Client, s.Recorder, source, s.DryRun)
+}
+
+// checksumAnnotationPrefix prefixes the pod template annotations that carry the
+// content hash of a consumed source.
+const checksumAnnotationPrefix = "replizieren.dev/checksum-"
+
+// checksumAnnotationKey returns the pod template annotation that carries the
+// content hash of the source, e.g. replizieren.dev/checksum-secret-db. Names
+// that do not fit into an annotation key are shortened and made unique by a
+// hash of the full name.
+func checksumAnnotationKey(source client.Object) string {
+	kind := "secret-"
+	if _, ok := source.(*corev1.ConfigMap); ok {
+		kind = "configmap-"
+	}
+	prefix := strings.TrimPrefix(checksumAnnotationPrefix, "replizieren.dev/") + kind
+	name := source.GetName()
+	if len(prefix)+len(name) > validation.LabelValueMaxLength {
+		sum := sha256.Sum256([]byte(name))
+		suffix := "-" + hex.EncodeToString(sum[:])[:10]
+		name = name[:validation.LabelValueMaxLength-len(prefix)-len(suffix)] + suffix
+	}
+	return checksumAnnotationPrefix + kind + name
+}
+
+// sync replicates the source to its target namespaces and triggers rollouts.
+// Failed targets are returned as error, so the source is retried with backoff.
+// Sources enqueued by a resync that did not change since are synced as resync.
+func (s *sourceSyncer) sync(ctx context.Context, source client.Object) (ctrl.Result, error) {
+	pending, resync := s.Resyncer.take(source)
+	results, requeueAfter, err := s.syncSource(ctx, source, resync)
+	if pending {
+		s.Resyncer.record(source, results, err)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, targetErrors(results)
+}
+
+// syncSource replicates the source and returns the result of every target and
+// when waves that are still in progress should be checked again. During a
+// resync the source did not change, so workloads are only restarted if a
+// replica had to be written.
+func (s *sourceSyncer) syncSource(ctx context.Context, source client.Object, resync bool) (map[string]targetResult, time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	if IsReplica(source) || IsRevision(source) {
+		return nil, 0, nil
+	}
+
+	writer := s.writer(source)
+	if paused, err := s.checkPaused(ctx, writer, source, resync); paused || err != nil {
+		return nil, 0, err
+	}
+
+	config, err := ResolveReplicationConfig(ctx, s.Client, source)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, ok := source.GetAnnotations()[RollbackKey]; ok {
+		if err := s.rollback(ctx, writer, source, config); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	replications, err := ReplicationsForSource(ctx, s.Client, source)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Sources that are neither replicated nor reloaded by a workload are most
+	// Secrets and ConfigMaps of the cluster, so return before the namespaces
+	// are listed for the maintenance windows
+	reloadOnly := config.SkipReplication && !config.RolloutOnUpdate
+	if reloadOnly {
+		if !resync {
+			logger.V(1).Info("Replication not set, skipping")
+		}
+		// Workloads may still have opted in to reloads of the source
+		optedIn, err := s.Reloads.optedIn(ctx, s.Client, source)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !optedIn {
+			return nil, 0, updateStatuses(ctx, writer, replications, nil, nil, nil)
+		}
+	}
+
+	gate, err := newWindowGate(ctx, s.Client, source, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+	var requeueAfter time.Duration
+	// hold requeues the source when a change held by a maintenance window
+	// may be applied
+	hold := func(opens time.Time) {
+		if opens.IsZero() {
+			return
+		}
+		if wait := opens.Sub(gate.now); requeueAfter == 0 || wait < requeueAfter {
+			requeueAfter = wait
+		}
+	}
+
+	if reloadOnly {
+		var report rolloutReport
+		if !resync || gate.restricted(source.GetNamespace()) {
+			if opens := gate.rolloutOpens(source.GetNamespace()); opens.Equal(gate.now) {
+				steps := []rolloutStep{restartStep(source, config)}
+				s.rollout(ctx, writer, source, steps)
+				if report, err = s.verifyRollouts(ctx, source, steps); err != nil {
+					return nil, 0, err
+				}
+				if report.Progressing {
+					hold(gate.now.Add(rolloutVerifyInterval))
+				}
+			} else if !resync {
+				hold(opens)
+			}
+		}
+		return nil, requeueAfter, updateStatuses(ctx, writer, replications, nil, nil, report.Failed)
+	}
+
+	var targetNamespaces []string
+	if !config.SkipReplication {
+		s.recordRevision(ctx, source, config)
+		targetNamespaces, err = ResolveTargetNamespaces(ctx, s.Client, config, source.GetNamespace())
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	results := make(map[string]targetResult, len(targetNamespaces))
+	written, bypassed := false, false
+	// rollout restarts workloads, verify checks the rollouts of the workloads
+	// restarted for the current content
+	var rollout, verify []rolloutStep
+	// replicate returns the rollout steps of the targets that were written.
+	// Targets restricted by maintenance windows are restarted whenever their
+	// window is open, so restarts held earlier are applied.
+	replicate := func(namespaces []string) []rolloutStep {
+		var steps []rolloutStep
+		for _, ns := range namespaces {
+			if result, held := s.holdReplica(ctx, source, ns, config, gate); held {
+				results[ns] = result
+				hold(result.WindowOpens)
+				continue
+			}
+			result := s.replicateTo(ctx, writer, source, ns, config)
+			if result.Err == nil && result.Outcome.Written() {
+				written = true
+				bypassed = bypassed || gate.bypassed(ns)
+			}
+			opens := gate.rolloutOpens(ns)
+			if result.Err == nil && result.Outcome != ReplicaConflict && opens.Equal(gate.now) {
+				verify = append(verify, restartStep(newReplica(source, ns, config), config))
+			}
+			if result.Err == nil && (result.Outcome.Written() || (gate.restricted(ns) && result.Outcome != ReplicaConflict)) {
+				if opens.Equal(gate.now) {
+					steps = append(steps, restartStep(newReplica(source, ns, config), config))
+				} else if result.Outcome.Written() {
+					result.WindowOpens = opens
+					hold(opens)
+				}
+			}
+			results[ns] = result
+		}
+		return steps
+	}
+
+	var waves []replicationv1alpha1.WaveStatus
+	if len(config.Waves) == 0 {
+		rollout = append(rollout, replicate(targetNamespaces)...)
+	} else {
+		var wavesRequeue time.Duration
+		waves, wavesRequeue, err = s.propagateWaves(ctx, source, config, targetNamespaces, results, resync,
+			func(namespaces []string) []rolloutStep {
+				replicate(namespaces)
+				steps := make([]rolloutStep, 0, len(namespaces))
+				for _, ns := range namespaces {
+					step := restartStep(newReplica(source, ns, config), config)
+					steps = append(steps, step)
+					// Restarts are idempotent, so every target of the wave is
+					// included to catch workloads that missed an earlier restart
+					if gate.rolloutOpens(ns).Equal(gate.now) {
+						rollout = append(rollout, step)
+					}
+				}
+				return steps
+			})
+		if err != nil {
+			return nil, 0, err
+		}
+		if wavesRequeue > 0 {
+			hold(gate.now.Add(wavesRequeue))
+		}
+	}
+
+	// Also trigger rollout in source namespace. When the source has targets,
+	// only restart if a replica changed, as reconciles triggered by replica
+	// events or Replications do not change the source.
+	sourceChanged := written || (len(targetNamespaces) == 0 && !resync)
+	if sourceChanged || gate.restricted(source.GetNamespace()) {
+		if opens := gate.rolloutOpens(source.GetNamespace()); opens.Equal(gate.now) {
+			rollout = append(rollout, restartStep(source, config))
+			bypassed = bypassed || (sourceChanged && gate.bypassed(source.GetNamespace()))
+		} else if sourceChanged {
+			hold(opens)
+		}
+	}
+	if gate.rolloutOpens(source.GetNamespace()).Equal(gate.now) {
+		verify = append(verify, restartStep(source, config))
+	}
+	s.rollout(ctx, writer, source, rollout)
+
+	report, err := s.verifyRollouts(ctx, source, verify)
+	if err != nil {
+		return nil, 0, err
+	}
+	if report.Progressing {
+		hold(gate.now.Add(rolloutVerifyInterval))
+	}
+
+	if bypassed && !resync {
+		logger.Info("Applied changes outside maintenance windows", "reason", source.GetAnnotations()[BreakGlassKey])
+		if s.Recorder != nil {
+			s.Recorder.Eventf(source, nil, corev1.EventTypeWarning, reasonWindowBypassed, "Replicate",
+				"Applied changes outside maintenance windows: %s", source.GetAnnotations()[BreakGlassKey])
+		}
+	}
+
+	return results, requeueAfter, updateStatuses(ctx, writer, replications, results, waves, report.Failed)
+}
+
+// holdReplica returns a pending result if a maintenance window holds the
+// update of the replica in the namespace. New replicas are never held.
+func (s *sourceSyncer) holdReplica(
+	ctx context.Context,
+	source client.Object,
+	ns string,
+	config ReplicationConfig,
+	gate *windowGate,
+) (targetResult, bool) {
+	opens := gate.writeOpens(ns)
+	if opens.Equal(gate.now) {
+		return targetResult{}, false
+	}
+	// Errors are left to replicateTo, which reports them
+	if outdated, err := replicaOutdated(ctx, s.Client, source, ns, config); err != nil || !outdated {
+		return targetResult{}, false
+	}
+	return targetResult{Namespace: ns, Outcome: ReplicaPending, WindowOpens: opens}, true
+}
+
+// replicateTo replicates the source into a single target namespace.
+func (s *sourceSyncer) replicateTo(
+	ctx context.Context,
+	writer client.Client,
+	source client.Object,
+	ns string,
+	config ReplicationConfig,
+) targetResult {
+	logger := log.FromContext(ctx)
+	kind := SourceKindOf(source)
+
+	outcome, err := ReplicateObject(ctx, writer, source, ns, config)
+	result := targetResult{Namespace: ns, Outcome: outcome, Hash: PayloadHash(newReplica(source, ns, config)), Err: err}
+	recordReplication(s.Recorder, source, ns, outcome, err, s.DryRun || IsDryRun(source))
+	if err != nil {
+		logger.Error(err, "Failed to replicate", "kind", kind, "namespace", ns)
+		replicaSyncErrorsTotal.WithLabelValues(string(kind), ns).Inc()
+		return result
+	}
+	switch outcome {
+	case ReplicaConflict:
+		logger.Info("Target already contains an object not managed by replizieren, skipping", "namespace", ns)
+	case ReplicaRestored:
+		logger.Info("Replica was modified outside of replizieren, restored", "namespace", ns)
+		s.recordDrift(source, newReplica(source, ns, config), driftModified)
+	}
+
+	if config.OnUpdateJob != nil && outcome != ReplicaConflict {
+		if outcome == ReplicaUpdated {
+			if err := s.runUpdateJob(ctx, writer, source, ns, result.Hash, config.OnUpdateJob); err != nil {
+				logger.Error(err, "Failed to start update job", "namespace", ns)
+				if s.Recorder != nil {
+					s.Recorder.Eventf(source, nil, corev1.EventTypeWarning, reasonUpdateJobFailed, "RunJob",
+						"Failed to start update job in namespace %s: %v", ns, err)
+				}
+			}
+		}
+		result.Job = s.updateJobStatus(ctx, source, ns, result.Hash)
+	}
+	return result
+}
+
+// propagateWaves replicates the waves of the source in order. A wave only
+// receives the change after the previous wave is replicated, its workloads
+// are healthy and its delay passed. Targets of later waves keep their previous
+// content and are reported as pending. It returns the state of every wave and
+// when to check again, zero if propagation finished or halted.
+func (s *sourceSyncer) propagateWaves(
+	ctx context.Context,
+	source client.Object,
+	config ReplicationConfig,
+	targets []string,
+	results map[string]targetResult,
+	resync bool,
+	replicate func(namespaces []string) []rolloutStep,
+) ([]replicationv1alpha1.WaveStatus, time.Duration, error) {
+	groups, err := assignWaves(ctx, s.Client, config.Waves, targets)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sourceRef := fmt.Sprintf("%s/%s/%s", SourceKindOf(source), source.GetNamespace(), source.GetName())
+	hash := PayloadHash(newReplica(source, source.GetNamespace(), config))
+	now := time.Now()
+	statuses := make([]replicationv1alpha1.WaveStatus, 0, len(groups))
+	var requeueAfter time.Duration
+	var since time.Time
+	blocked := false
+	for i, wave := range groups {
+		status := replicationv1alpha1.WaveStatus{
+			Name:       wave.Name,
+			State:      replicationv1alpha1.WaveStatePending,
+			Namespaces: int32(len(wave.Targets)),
+		}
+		if !blocked && i > 0 {
+			if wait := since.Add(wave.Delay).Sub(now); wait > 0 {
+				status.Message = fmt.Sprintf("Starts in %s", wait.Round(time.Second))
+				requeueAfter = wait
+				blocked = true
+			}
+		}
+		if blocked {
+			for _, ns := range wave.Targets {
+				results[ns] = targetResult{Namespace: ns, Outcome: ReplicaPending}
+			}
+			statuses = append(statuses, status)
+			continue
+		}
+
+		steps := replicate(wave.Targets)
+		baseline := func(restarts map[string]int32) map[string]int32 {
+			return s.Waves.restartBaseline(sourceRef, wave.Name, hash, restarts)
+		}
+		status.State, status.Message, err = s.waveState(ctx, source, wave.Targets, steps, results, baseline)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch status.State {
+		case replicationv1alpha1.WaveStateHealthy:
+			since = s.Waves.healthySince(sourceRef, wave.Name, hash, now)
+		case replicationv1alpha1.WaveStateProgressing:
+			s.Waves.unhealthy(sourceRef, wave.Name)
+			requeueAfter = wavePollInterval
+			blocked = true
+		case replicationv1alpha1.WaveStateHalted:
+			s.Waves.unhealthy(sourceRef, wave.Name)
+			blocked = true
+			if !resync {
+				log.FromContext(ctx).Info("Wave failed, halting propagation", "wave", wave.Name, "reason", status.Message)
+				if s.Recorder != nil {
+					s.Recorder.Eventf(source, nil, corev1.EventTypeWarning, reasonWaveHalted, "Propagate",
+						"Wave %s failed, later waves keep the previous content: %s", wave.Name, status.Message)
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, requeueAfter, nil
+}
+
+// waveState returns the state of a wave that received the change.
+func (s *sourceSyncer) waveState(
+	ctx context.Context,
+	source client.Object,
+	targets []string,
+	steps []rolloutStep,
+	results map[string]targetResult,
+	baseline func(restarts map[string]int32) map[string]int32,
+) (replicationv1alpha1.WaveState, string, error) {
+	var failed, held int
+	for _, ns := range targets {
+		if results[ns].Err != nil {
+			failed++
+		} else if results[ns].Outcome == ReplicaPending || !results[ns].WindowOpens.IsZero() {
+			held++
+		}
+	}
+	if failed > 0 {
+		return replicationv1alpha1.WaveStateProgressing, fmt.Sprintf("%d of %d targets are not in sync", failed, len(targets)), nil
+	}
+	if held > 0 {
+		return replicationv1alpha1.WaveStateProgressing,
+			fmt.Sprintf("%d of %d targets wait for their maintenance window", held, len(targets)), nil
+	}
+	return checkWaveHealth(ctx, s.Client, s.WorkloadKinds, checksumAnnotationKey(source), steps, baseline)
+}
+
+// rollout restarts the workloads of the steps, in the background if a
+// scheduler is configured.
+func (s *sourceSyncer) rollout(ctx context.Context, writer client.Client, source client.Object, steps []rolloutStep) {
+	seed := s.Seeds.firstSync(source)
+	if len(steps) == 0 {
+		return
+	}
+	plan := &rolloutPlan{
+		Source:        fmt.Sprintf("%s/%s/%s", SourceKindOf(source), source.GetNamespace(), source.GetName()),
+		Client:        writer,
+		Kinds:         s.WorkloadKinds,
+		AnnotationKey: checksumAnnotationKey(source),
+		Steps:         steps,
+		Checksum:      rolloutChecksum(source, steps),
+		Seed:          seed,
+	}
+	if !s.DryRun && !IsDryRun(source) {
+		plan.Object, plan.Recorder, plan.Tracker = source, s.Recorder, s.RolloutTracker
+	}
+	if s.Rollouts == nil {
+		plan.run(ctx)
+		return
+	}
+	s.Rollouts.Schedule(source, plan)
+}
+
+// rolloutChecksum identifies a rollout by the content and the annotations of
+// the source and the namespaces it restarts. Syncs of waves in progress and
+// of held restarts schedule the same plan again, which keeps the rollout
+// running.
+func rolloutChecksum(source client.Object, steps []rolloutStep) string {
+	h := sha256.New()
+	h.Write([]byte(KeysHash(source, nil)))
+	annotations := source.GetAnnotations()
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		if strings.HasPrefix(k, annotationPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k + "=" + annotations[k]))
+	}
+	for _, step := range steps {
+		h.Write([]byte{0})
+		h.Write([]byte(step.Namespace))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkPaused returns true if replication of the source is paused by
+// annotation or cluster-wide. The pause is reported on the Replications of the
+// source and, unless resyncing, as event.
+func (s *sourceSyncer) checkPaused(ctx context.Context, writer client.Client, source client.Object, resync bool) (bool, error) {
+	reason, message := reasonPausedBySource, fmt.Sprintf("Replication is paused by annotation %s", PausedKey)
+	if !IsPaused(source) {
+		paused, globalMessage, err := s.Pause.GloballyPaused(ctx, s.Client)
+		if err != nil || !paused {
+			return false, err
+		}
+		reason, message = reasonPausedGlobally, globalMessage
+	}
+
+	if !resync {
+		log.FromContext(ctx).Info("Replication paused, skipping", "reason", message)
+		if s.Recorder != nil {
+			s.Recorder.Eventf(source, nil, corev1.EventTypeNormal, reasonPaused, "Replicate",
+				"%s, changes are not propagated", message)
+		}
+	}
+
+	replications, err := ReplicationsForSource(ctx, s.Client, source)
+	if err != nil {
+		return true, err
+	}
+	return true, markPaused(ctx, writer, replications, reason, message)
+}
+
+// updateStatuses reports the results of a sync on the Replications of the source.
+func updateStatuses(
+	ctx context.Context,
+	c client.Client,
+	replications []replicationv1alpha1.Replication,
+	results map[string]targetResult,
+	waves []replicationv1alpha1.WaveStatus,
+	failedRollouts []string,
+) error {
+	for i := range replications {
+		if err := updateReplicationStatus(ctx, c, &replications[i], results, waves, failedRollouts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
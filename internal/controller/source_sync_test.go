@@ -0,0 +1,254 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// syncFixture runs the sourceSyncer against a fake client. The namespaces of
+// the given objects are created along with them, as they would exist in a
+// cluster.
+type syncFixture struct {
+	t        *testing.T
+	ctx      context.Context
+	scheme   *runtime.Scheme
+	client   client.Client
+	recorder *events.FakeRecorder
+	syncer   *sourceSyncer
+}
+
+func newSyncFixture(t *testing.T, objects ...client.Object) *syncFixture {
+	t.Helper()
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	_ = replicationv1alpha1.AddToScheme(testScheme)
+
+	namespaces := map[string]bool{}
+	for _, obj := range objects {
+		if ns, ok := obj.(*corev1.Namespace); ok {
+			namespaces[ns.Name] = true
+		}
+	}
+	for _, obj := range append([]client.Object(nil), objects...) {
+		if ns := obj.GetNamespace(); ns != "" && !namespaces[ns] {
+			namespaces[ns] = true
+			objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(testScheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&replicationv1alpha1.Replication{}, &appsv1.Deployment{}, &batchv1.Job{}).
+		Build()
+	recorder := events.NewFakeRecorder(100)
+	return &syncFixture{
+		t:        t,
+		ctx:      context.Background(),
+		scheme:   testScheme,
+		client:   c,
+		recorder: recorder,
+		syncer:   &sourceSyncer{Client: c, SyncOptions: SyncOptions{Recorder: recorder}},
+	}
+}
+
+// sync runs syncSource for the source and fails the test on errors.
+func (f *syncFixture) sync(source client.Object) (map[string]targetResult, time.Duration) {
+	f.t.Helper()
+	results, requeueAfter, err := f.syncer.syncSource(f.ctx, source, false)
+	if err != nil {
+		f.t.Fatalf("unexpected error: %v", err)
+	}
+	return results, requeueAfter
+}
+
+// get refreshes the object from the fake client.
+func (f *syncFixture) get(obj client.Object) {
+	f.t.Helper()
+	if err := f.client.Get(f.ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		f.t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// update writes the object to the fake client.
+func (f *syncFixture) update(obj client.Object) {
+	f.t.Helper()
+	if err := f.client.Update(f.ctx, obj); err != nil {
+		f.t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// exists reports whether the fake client holds the object.
+func (f *syncFixture) exists(obj client.Object, namespace, name string) bool {
+	return f.client.Get(f.ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj) == nil
+}
+
+// secretData returns the key of the Secret, or an empty string if the Secret
+// does not exist.
+func (f *syncFixture) secretData(namespace, name, key string) string {
+	var secret corev1.Secret
+	if !f.exists(&secret, namespace, name) {
+		return ""
+	}
+	return string(secret.Data[key])
+}
+
+// checksum returns the checksum of the source on the pod template of the
+// Deployment.
+func (f *syncFixture) checksum(source client.Object, namespace, name string) string {
+	f.t.Helper()
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	f.get(deploy)
+	return deploy.Spec.Template.Annotations[checksumAnnotationKey(source)]
+}
+
+// expectEvents checks that the recorder received events starting with the
+// prefixes in order and nothing else.
+func (f *syncFixture) expectEvents(prefixes ...string) {
+	f.t.Helper()
+	for _, prefix := range prefixes {
+		select {
+		case event := <-f.recorder.Events:
+			if !strings.HasPrefix(event, prefix) {
+				f.t.Errorf("expected event %q, got %q", prefix, event)
+			}
+		default:
+			f.t.Errorf("expected event %q", prefix)
+		}
+	}
+	for len(f.recorder.Events) > 0 {
+		f.t.Errorf("unexpected event %q", <-f.recorder.Events)
+	}
+}
+
+func TestSyncSource_MissingTargetNamespace(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Data:       map[string][]byte{"password": []byte("v1")},
+	}
+	replication := &replicationv1alpha1.Replication{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Spec: replicationv1alpha1.ReplicationSpec{
+			Source:  replicationv1alpha1.SourceReference{Kind: replicationv1alpha1.SourceKindSecret, Name: "creds"},
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"present", "missing"}},
+		},
+	}
+	f := newSyncFixture(t, source, replication, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "present"}})
+
+	// The missing namespace is neither a failure nor retried
+	results, requeueAfter := f.sync(source)
+	if _, ok := results["missing"]; ok || results["present"].Outcome != ReplicaCreated {
+		t.Errorf("expected only the existing namespace to be replicated to, got %+v", results)
+	}
+	if requeueAfter != 0 {
+		t.Errorf("expected no requeue, got %v", requeueAfter)
+	}
+	f.expectEvents("Normal Replicated Created replica in namespace present")
+	f.get(replication)
+	if meta.IsStatusConditionTrue(replication.Status.Conditions, replicationv1alpha1.ConditionDegraded) {
+		t.Error("expected a missing namespace to not degrade the Replication")
+	}
+}
+
+func TestSyncSource_UnrelatedSourceListsNoNamespaces(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "source-ns"},
+		Data:       map[string]string{"key": "value"},
+	}
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	_ = replicationv1alpha1.AddToScheme(testScheme)
+	builder := fake.NewClientBuilder().WithScheme(testScheme).
+		WithObjects(source, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "source-ns"}})
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}, &batchv1.CronJob{}} {
+		builder = builder.WithIndex(obj, reloadIndexField, indexReloads)
+	}
+	var namespaceLists int
+	c := interceptor.NewClient(builder.Build(), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if _, ok := list.(*corev1.NamespaceList); ok {
+				namespaceLists++
+			}
+			return c.List(ctx, list, opts...)
+		},
+	})
+	syncer := &sourceSyncer{Client: c, SyncOptions: SyncOptions{Recorder: events.NewFakeRecorder(10), Reloads: &ReloadIndex{}}}
+
+	// Neither replicated nor reloaded, so the maintenance windows of the
+	// namespaces do not matter
+	results, requeueAfter, err := syncer.syncSource(context.Background(), source, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 0 || requeueAfter != 0 {
+		t.Errorf("expected nothing to be synced, got %+v and %v", results, requeueAfter)
+	}
+	if namespaceLists != 0 {
+		t.Errorf("expected no namespaces to be listed, got %d lists", namespaceLists)
+	}
+}
+
+func TestRolloutChecksum(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "creds",
+			Namespace:   "source-ns",
+			Annotations: map[string]string{WavesKey: "dev; prod"},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	steps := []rolloutStep{{Namespace: "dev"}}
+	checksum := rolloutChecksum(source, steps)
+
+	// Syncs of a wave in progress schedule the same plan
+	source.ResourceVersion = "2"
+	source.Annotations["kubectl.kubernetes.io/last-applied-configuration"] = "{}"
+	if rolloutChecksum(source, []rolloutStep{{Namespace: "dev"}}) != checksum {
+		t.Error("expected an unchanged source to produce the same checksum")
+	}
+
+	if rolloutChecksum(source, []rolloutStep{{Namespace: "dev"}, {Namespace: "prod"}}) == checksum {
+		t.Error("expected the next wave to change the checksum")
+	}
+	source.Annotations[RolloutSelectorKey] = "tier=frontend"
+	if rolloutChecksum(source, steps) == checksum {
+		t.Error("expected a changed rollout annotation to change the checksum")
+	}
+	delete(source.Annotations, RolloutSelectorKey)
+	source.Data["password"] = []byte("v2")
+	if rolloutChecksum(source, steps) == checksum {
+		t.Error("expected changed content to change the checksum")
+	}
+}
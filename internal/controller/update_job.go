@@ -0,0 +1,315 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// OnUpdateJobKey references the template of a Job that runs in every target
+// namespace after its replica was updated, e.g. "cronjob/db-migrate".
+const OnUpdateJobKey = "replizieren.dev/on-update-job"
+
+// UpdateJobLabel marks Jobs created for updated replicas. Its value is the
+// lower-case kind of the source.
+const UpdateJobLabel = "replizieren.dev/update-job"
+
+// UpdateJobTemplateKey is the key of a ConfigMap that holds a Job manifest.
+const UpdateJobTemplateKey = "job.yaml"
+
+// AllowUpdateJobsKey opts a Namespace in to update Jobs. Templates come from
+// the namespace of the source, so Jobs only run in target namespaces that
+// allow it.
+const AllowUpdateJobsKey = "replizieren.dev/allow-update-jobs"
+
+// updateJobTTL removes finished update Jobs after a day, unless the template
+// sets ttlSecondsAfterFinished.
+const updateJobTTL = 24 * 60 * 60
+
+// Event reasons for update Jobs
+const (
+	reasonUpdateJobFailed    = "UpdateJobFailed"
+	reasonUpdateJobSucceeded = "UpdateJobSucceeded"
+)
+
+// Kinds of Job templates
+const (
+	jobTemplateCronJob   = "cronjob"
+	jobTemplateConfigMap = "configmap"
+)
+
+// JobTemplateRef references a Job template in the namespace of a source.
+type JobTemplateRef struct {
+	// Kind is "cronjob" for the job template of a CronJob, usually a
+	// suspended one, or "configmap" for a Job manifest in a ConfigMap.
+	Kind string
+	Name string
+}
+
+// ParseJobTemplateRef parses the on-update-job annotation, e.g.
+// "cronjob/db-migrate" or "configmap/cache-rebuild". Kinds are matched
+// case-insensitively.
+func ParseJobTemplateRef(value string) (*JobTemplateRef, error) {
+	kind, name, ok := strings.Cut(strings.TrimSpace(value), "/")
+	kind = strings.ToLower(kind)
+	if !ok || name == "" || (kind != jobTemplateCronJob && kind != jobTemplateConfigMap) {
+		return nil, fmt.Errorf("invalid job template %q: expected cronjob/<name> or configmap/<name>", value)
+	}
+	return &JobTemplateRef{Kind: kind, Name: name}, nil
+}
+
+// loadJobTemplate returns the Job template referenced in the namespace.
+func loadJobTemplate(ctx context.Context, c client.Client, namespace string, ref *JobTemplateRef) (*batchv1.JobTemplateSpec, error) {
+	key := client.ObjectKey{Namespace: namespace, Name: ref.Name}
+	if ref.Kind == jobTemplateCronJob {
+		var cronJob batchv1.CronJob
+		if err := c.Get(ctx, key, &cronJob); err != nil {
+			return nil, err
+		}
+		return &cronJob.Spec.JobTemplate, nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := c.Get(ctx, key, &cm); err != nil {
+		return nil, err
+	}
+	manifest, ok := cm.Data[UpdateJobTemplateKey]
+	if !ok {
+		return nil, fmt.Errorf("ConfigMap %s/%s has no key %s", namespace, ref.Name, UpdateJobTemplateKey)
+	}
+	var job batchv1.Job
+	if err := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096).Decode(&job); err != nil {
+		return nil, fmt.Errorf("invalid Job in ConfigMap %s/%s: %w", namespace, ref.Name, err)
+	}
+	return &batchv1.JobTemplateSpec{ObjectMeta: job.ObjectMeta, Spec: job.Spec}, nil
+}
+
+// checkJobTemplate rejects templates that would gain privileges in the target
+// namespace: its service accounts, the host namespaces, host paths and
+// privileged containers.
+func checkJobTemplate(spec *corev1.PodSpec) error {
+	var problems []string
+	if spec.ServiceAccountName != "" || spec.DeprecatedServiceAccount != "" {
+		problems = append(problems, "sets a service account")
+	}
+	if spec.HostNetwork || spec.HostPID || spec.HostIPC {
+		problems = append(problems, "uses host namespaces")
+	}
+	for _, volume := range spec.Volumes {
+		if volume.HostPath != nil {
+			problems = append(problems, fmt.Sprintf("mounts host path %s", volume.HostPath.Path))
+		}
+	}
+	for _, container := range append(slices.Clone(spec.InitContainers), spec.Containers...) {
+		if sc := container.SecurityContext; sc != nil &&
+			((sc.Privileged != nil && *sc.Privileged) || (sc.AllowPrivilegeEscalation != nil && *sc.AllowPrivilegeEscalation)) {
+			problems = append(problems, fmt.Sprintf("container %s is privileged", container.Name))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("job template is not allowed: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// updateJobName returns the name of the Job run for a replica of the source
+// with the content hash, so every change runs the Job once per namespace.
+func updateJobName(source client.Object, hash string) string {
+	sum := sha256.Sum256([]byte(string(SourceKindOf(source)) + "/" + hash))
+	suffix := "-" + hex.EncodeToString(sum[:])[:10]
+	name := source.GetName()
+	if len(name)+len(suffix) > validation.DNS1123LabelMaxLength {
+		name = name[:validation.DNS1123LabelMaxLength-len(suffix)]
+	}
+	return strings.TrimRight(name, "-.") + suffix
+}
+
+// newUpdateJob builds the Job run in the namespace of a replica with the hash.
+func newUpdateJob(source client.Object, namespace, hash string, template *batchv1.JobTemplateSpec) *batchv1.Job {
+	labels := map[string]string{}
+	for k, v := range template.Labels {
+		labels[k] = v
+	}
+	labels[UpdateJobLabel] = strings.ToLower(string(SourceKindOf(source)))
+
+	annotations := map[string]string{}
+	for k, v := range template.Annotations {
+		annotations[k] = v
+	}
+	annotations[SourceAnnotation] = source.GetNamespace() + "/" + source.GetName()
+	annotations[HashAnnotation] = hash
+
+	job := &batchv1.Job{Spec: *template.Spec.DeepCopy()}
+	job.Name = updateJobName(source, hash)
+	job.Namespace = namespace
+	job.Labels = labels
+	job.Annotations = annotations
+	if job.Spec.ManualSelector == nil || !*job.Spec.ManualSelector {
+		// The selector of a manifest that was exported from a Job belongs to that Job
+		job.Spec.Selector = nil
+	}
+	if job.Spec.TTLSecondsAfterFinished == nil {
+		ttl := int32(updateJobTTL)
+		job.Spec.TTLSecondsAfterFinished = &ttl
+	}
+	return job
+}
+
+// runUpdateJob creates the Job for the replica of the source in the namespace,
+// unless it already ran for the hash. Namespaces that did not opt in and
+// templates that would gain privileges are reported as errors.
+func (s *sourceSyncer) runUpdateJob(
+	ctx context.Context,
+	writer client.Client,
+	source client.Object,
+	namespace, hash string,
+	ref *JobTemplateRef,
+) error {
+	var existing batchv1.Job
+	err := s.Get(ctx, client.ObjectKey{Namespace: namespace, Name: updateJobName(source, hash)}, &existing)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	var target corev1.Namespace
+	if err := s.Get(ctx, client.ObjectKey{Name: namespace}, &target); err != nil {
+		return err
+	}
+	if target.Annotations[AllowUpdateJobsKey] != "true" {
+		return fmt.Errorf("namespace %s does not allow update jobs, annotate it with %s: \"true\"", namespace, AllowUpdateJobsKey)
+	}
+
+	template, err := loadJobTemplate(ctx, s.Client, source.GetNamespace(), ref)
+	if err != nil {
+		return err
+	}
+	if err := checkJobTemplate(&template.Spec.Template.Spec); err != nil {
+		return err
+	}
+	job := newUpdateJob(source, namespace, hash, template)
+	if err := writer.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	log.FromContext(ctx).Info("Started update job", "namespace", namespace, "job", job.Name)
+	return nil
+}
+
+// updateJobStatus returns the state of the Job run for the replica of the
+// source with the hash, nil if there is none.
+func (s *sourceSyncer) updateJobStatus(ctx context.Context, source client.Object, namespace, hash string) *replicationv1alpha1.JobStatus {
+	var job batchv1.Job
+	if err := s.Get(ctx, client.ObjectKey{Namespace: namespace, Name: updateJobName(source, hash)}, &job); err != nil {
+		return nil
+	}
+	status := &replicationv1alpha1.JobStatus{Name: job.Name, State: replicationv1alpha1.JobStateRunning}
+	if condition := jobFinishedCondition(&job); condition != nil {
+		status.CompletionTime = &condition.LastTransitionTime
+		status.State = replicationv1alpha1.JobStateSucceeded
+		if condition.Type == batchv1.JobFailed {
+			status.State = replicationv1alpha1.JobStateFailed
+			status.Message = condition.Message
+		}
+	}
+	return status
+}
+
+// jobFinishedCondition returns the Complete or Failed condition of a finished
+// Job, nil while it is running.
+func jobFinishedCondition(job *batchv1.Job) *batchv1.JobCondition {
+	for i, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) &&
+			condition.Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// updateJobPredicate selects the update Jobs of sources of the kind.
+func updateJobPredicate(kind replicationv1alpha1.SourceKind) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetLabels()[UpdateJobLabel] == strings.ToLower(string(kind))
+	})
+}
+
+// updateJobHandler maps update Jobs to their source, so its status reflects
+// the Job. Finished Jobs are recorded as events on the source.
+func (s *sourceSyncer) updateJobHandler() handler.EventHandler {
+	return handler.Funcs{
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			oldJob, ok := e.ObjectOld.(*batchv1.Job)
+			if !ok {
+				return
+			}
+			job, ok := e.ObjectNew.(*batchv1.Job)
+			if !ok || jobFinishedCondition(oldJob) != nil || jobFinishedCondition(job) == nil {
+				return
+			}
+			s.recordFinishedUpdateJob(ctx, job)
+			enqueueSource(job, q)
+		},
+		DeleteFunc: func(_ context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueSource(e.Object, q)
+		},
+	}
+}
+
+// recordFinishedUpdateJob records the outcome of an update Job on its source.
+func (s *sourceSyncer) recordFinishedUpdateJob(ctx context.Context, job *batchv1.Job) {
+	ref, ok := SourceOf(job)
+	if !ok || s.Recorder == nil {
+		return
+	}
+	kind := replicationv1alpha1.SourceKindSecret
+	if job.Labels[UpdateJobLabel] == strings.ToLower(string(replicationv1alpha1.SourceKindConfigMap)) {
+		kind = replicationv1alpha1.SourceKindConfigMap
+	}
+	source := newSourceObject(kind)
+	if err := s.Get(ctx, ref, source); err != nil {
+		return
+	}
+
+	condition := jobFinishedCondition(job)
+	if condition.Type == batchv1.JobFailed {
+		log.FromContext(ctx).Info("Update job failed", "namespace", job.Namespace, "job", job.Name, "reason", condition.Message)
+		s.Recorder.Eventf(source, job, corev1.EventTypeWarning, reasonUpdateJobFailed, "RunJob",
+			"Job %s/%s failed: %s", job.Namespace, job.Name, condition.Message)
+		return
+	}
+	s.Recorder.Eventf(source, job, corev1.EventTypeNormal, reasonUpdateJobSucceeded, "RunJob",
+		"Job %s/%s completed", job.Namespace, job.Name)
+}
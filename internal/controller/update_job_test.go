@@ -0,0 +1,218 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestParseJobTemplateRef(t *testing.T) {
+	ref, err := ParseJobTemplateRef("CronJob/db-migrate")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Kind != "cronjob" || ref.Name != "db-migrate" {
+		t.Errorf("unexpected reference %+v", ref)
+	}
+
+	for _, value := range []string{"", "db-migrate", "cronjob/", "deployment/db-migrate"} {
+		if _, err := ParseJobTemplateRef(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestUpdateJobName(t *testing.T) {
+	source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 70), Namespace: "source-ns"}}
+	name := updateJobName(source, "v1")
+	if len(name) > validation.DNS1123LabelMaxLength {
+		t.Errorf("expected name of at most %d characters, got %q", validation.DNS1123LabelMaxLength, name)
+	}
+	if name == updateJobName(source, "v2") {
+		t.Error("expected different names for different hashes")
+	}
+	if name == updateJobName(&corev1.ConfigMap{ObjectMeta: source.ObjectMeta}, "v1") {
+		t.Error("expected different names for a Secret and a ConfigMap")
+	}
+}
+
+func TestLoadJobTemplate_ConfigMap(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	ctx := context.Background()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cache-rebuild", Namespace: "source-ns"},
+		Data: map[string]string{UpdateJobTemplateKey: `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  labels:
+    app: cache
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: rebuild
+          image: cache-tools
+`},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(cm).Build()
+
+	template, err := loadJobTemplate(ctx, c, "source-ns", &JobTemplateRef{Kind: jobTemplateConfigMap, Name: "cache-rebuild"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if template.Labels["app"] != "cache" || template.Spec.Template.Spec.Containers[0].Image != "cache-tools" {
+		t.Errorf("unexpected template %+v", template)
+	}
+
+	cm.Data = map[string]string{"other": "value"}
+	if err := c.Update(ctx, cm); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := loadJobTemplate(ctx, c, "source-ns", &JobTemplateRef{Kind: jobTemplateConfigMap, Name: "cache-rebuild"}); err == nil {
+		t.Error("expected error for a ConfigMap without a Job manifest")
+	}
+}
+
+func TestCheckJobTemplate(t *testing.T) {
+	if err := checkJobTemplate(&corev1.PodSpec{Containers: []corev1.Container{{Name: "migrate"}}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := checkJobTemplate(&corev1.PodSpec{
+		ServiceAccountName: "admin",
+		HostNetwork:        true,
+		Volumes:            []corev1.Volume{{Name: "root", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}}}},
+		InitContainers:     []corev1.Container{{Name: "init", SecurityContext: &corev1.SecurityContext{AllowPrivilegeEscalation: pointerTo(true)}}},
+		Containers:         []corev1.Container{{Name: "migrate", SecurityContext: &corev1.SecurityContext{Privileged: pointerTo(true)}}},
+	})
+	if err == nil {
+		t.Fatal("expected privileged template to be rejected")
+	}
+	for _, problem := range []string{"service account", "host namespaces", "host path /", "container init", "container migrate"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected %q to be reported, got %v", problem, err)
+		}
+	}
+}
+
+func TestSyncSource_OnUpdateJob(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db-creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey:   "target-ns",
+				OnUpdateJobKey: "cronjob/db-migrate",
+			},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-migrate", Namespace: "source-ns"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "@yearly",
+			Suspend:  pointerTo(true),
+			JobTemplate: batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers:    []corev1.Container{{Name: "migrate", Image: "migrations"}},
+			}}}},
+		},
+	}
+	target := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "target-ns"}}
+	f := newSyncFixture(t, source, cronJob, target)
+
+	jobs := func() []batchv1.Job {
+		var list batchv1.JobList
+		if err := f.client.List(f.ctx, &list, client.InNamespace("target-ns")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return list.Items
+	}
+
+	// Creating the replica does not run the Job
+	f.sync(source)
+	if len(jobs()) != 0 {
+		t.Fatal("expected no Job for a new replica")
+	}
+
+	// Jobs only run in namespaces that opted in
+	source.Data["password"] = []byte("v2")
+	f.sync(source)
+	if len(jobs()) != 0 {
+		t.Fatal("expected no Job in a namespace that did not opt in")
+	}
+	f.expectEvents(
+		"Normal Replicated Created replica in namespace target-ns",
+		"Normal Replicated Updated replica in namespace target-ns",
+		"Warning UpdateJobFailed Failed to start update job in namespace target-ns: namespace target-ns does not allow update jobs",
+	)
+	target.Annotations = map[string]string{AllowUpdateJobsKey: "true"}
+	f.update(target)
+
+	source.Data["password"] = []byte("v3")
+	results, _ := f.sync(source)
+	created := jobs()
+	if len(created) != 1 {
+		t.Fatalf("expected one Job after the replica was updated, got %d", len(created))
+	}
+	job := created[0]
+	if job.Spec.Template.Spec.Containers[0].Image != "migrations" {
+		t.Error("expected Job to be created from the job template of the CronJob")
+	}
+	if job.Labels[UpdateJobLabel] != "secret" || job.Annotations[SourceAnnotation] != "source-ns/db-creds" {
+		t.Errorf("expected Job to reference its source, got labels %v annotations %v", job.Labels, job.Annotations)
+	}
+	if ttl := job.Spec.TTLSecondsAfterFinished; ttl == nil || *ttl != updateJobTTL {
+		t.Errorf("expected finished Job to be removed after a day, got %v", ttl)
+	}
+	if got := results["target-ns"].Job; got == nil || got.Name != job.Name || got.State != replicationv1alpha1.JobStateRunning {
+		t.Errorf("expected running Job in the result, got %+v", got)
+	}
+
+	job.Status.Conditions = []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded",
+	}}
+	if err := f.client.Status().Update(f.ctx, &job); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Later syncs report the Job without running it again
+	results, _ = f.sync(source)
+	if len(jobs()) != 1 {
+		t.Error("expected the Job to run once per change")
+	}
+	if got := results["target-ns"].Job; got == nil || got.State != replicationv1alpha1.JobStateFailed || got.Message != "BackoffLimitExceeded" {
+		t.Errorf("expected failed Job in the result, got %+v", got)
+	}
+}
@@ -0,0 +1,469 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+// Annotations that define the propagation waves of a source.
+const (
+	WavesKey     = "replizieren.dev/waves"
+	WaveDelayKey = "replizieren.dev/wave-delay"
+)
+
+// Event and condition reasons for waves
+const (
+	reasonWaveHalted       = "WaveHalted"
+	reasonWavesProgressing = "WavesProgressing"
+	reasonWavesCompleted   = "WavesCompleted"
+)
+
+// unassignedWaveName names the implicit last wave of the targets that are not
+// part of any wave.
+const unassignedWaveName = "unassigned"
+
+// wavePollInterval is the interval in which a wave whose workloads are not
+// healthy yet is checked again.
+const wavePollInterval = 30 * time.Second
+
+// Wave is a group of target namespaces that receives changes of a source
+// together, after the previous wave is healthy.
+type Wave struct {
+	Name string
+	// Namespaces lists the namespaces of the wave by name or glob pattern.
+	Namespaces []string
+	// Selector selects the namespaces of the wave by label, it may be nil.
+	Selector labels.Selector
+	// Delay is the minimum time between the previous wave becoming healthy
+	// and this wave receiving a change.
+	Delay time.Duration
+}
+
+// matches returns true if the wave selects the namespace.
+func (w Wave) matches(namespace string, namespaceLabels labels.Set) bool {
+	if matchesNamespacePattern(w.Namespaces, namespace) {
+		return true
+	}
+	return w.Selector != nil && w.Selector.Matches(namespaceLabels)
+}
+
+// ParseWaves parses the waves annotation, e.g. "dev; staging=staging-*,qa;
+// prod-*". Waves are separated by semicolons and list their namespaces by name
+// or glob pattern, optionally prefixed by the name of the wave. Unnamed waves
+// are named by position, e.g. "wave-3". Every wave but the first waits for
+// delay after the previous wave became healthy.
+func ParseWaves(value, delay string) ([]Wave, error) {
+	var d time.Duration
+	if delay != "" {
+		var err error
+		if d, err = time.ParseDuration(delay); err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid wave delay %q: must be a non-negative duration", delay)
+		}
+	}
+
+	var waves []Wave
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, list, named := strings.Cut(entry, "=")
+		if !named {
+			name, list = fmt.Sprintf("wave-%d", len(waves)+1), entry
+		}
+		name = strings.TrimSpace(name)
+		namespaces := SplitList(list)
+		if name == "" || len(namespaces) == 0 {
+			return nil, fmt.Errorf("invalid wave %q: expected [name=]namespace,...", entry)
+		}
+		if slices.ContainsFunc(waves, func(w Wave) bool { return w.Name == name }) {
+			return nil, fmt.Errorf("duplicate wave %q", name)
+		}
+		for _, ns := range namespaces {
+			if _, err := path.Match(ns, ""); err != nil {
+				return nil, fmt.Errorf("invalid namespace pattern %q in wave %q", ns, name)
+			}
+		}
+		wave := Wave{Name: name, Namespaces: namespaces}
+		if len(waves) > 0 {
+			wave.Delay = d
+		}
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+// wavesFromAPI converts the waves of a Replication or ReplicationPolicy.
+func wavesFromAPI(waves []replicationv1alpha1.Wave) []Wave {
+	var result []Wave
+	for _, w := range waves {
+		wave := Wave{Name: w.Name, Namespaces: w.Namespaces}
+		if w.NamespaceSelector != nil {
+			wave.Selector = selectorOrNothing(w.NamespaceSelector)
+		}
+		if w.Delay != nil {
+			wave.Delay = w.Delay.Duration
+		}
+		result = append(result, wave)
+	}
+	return result
+}
+
+// waveTargets is a wave with the target namespaces that belong to it.
+type waveTargets struct {
+	Wave
+	Targets []string
+}
+
+// assignWaves groups the target namespaces by the first wave that selects
+// them. Targets that no wave selects form an implicit last wave.
+func assignWaves(ctx context.Context, c client.Client, waves []Wave, targets []string) ([]waveTargets, error) {
+	namespaceLabels := map[string]labels.Set{}
+	if slices.ContainsFunc(waves, func(w Wave) bool { return w.Selector != nil }) {
+		var nsList corev1.NamespaceList
+		if err := c.List(ctx, &nsList); err != nil {
+			return nil, err
+		}
+		for _, ns := range nsList.Items {
+			namespaceLabels[ns.Name] = ns.Labels
+		}
+	}
+
+	groups := make([]waveTargets, len(waves)+1)
+	for i, wave := range waves {
+		groups[i].Wave = wave
+	}
+	groups[len(waves)].Name = unassignedWaveName
+	for _, ns := range targets {
+		i := slices.IndexFunc(waves, func(w Wave) bool { return w.matches(ns, namespaceLabels[ns]) })
+		if i < 0 {
+			i = len(waves)
+		}
+		groups[i].Targets = append(groups[i].Targets, ns)
+	}
+	if len(groups[len(waves)].Targets) == 0 {
+		groups = groups[:len(waves)]
+	}
+	return groups, nil
+}
+
+// replicaExists returns true if the target namespace contains an object with
+// the name of the source.
+func replicaExists(ctx context.Context, c client.Client, source client.Object, namespace string) (bool, error) {
+	existing := newSourceObject(SourceKindOf(source))
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: source.GetName()}, existing)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkWaveHealth checks the workloads of a wave that are restarted for the
+// change. Workloads that are not rolled out yet keep the wave progressing. A
+// wave fails when a workload exceeds its progress deadline or containers of
+// pods restarted for the change restart. Container restarts count from the
+// baseline, the restarts the pods had when the wave started; without a
+// baseline all restarts count.
+func checkWaveHealth(
+	ctx context.Context,
+	c client.Client,
+	kinds []WorkloadKind,
+	annotationKey string,
+	steps []rolloutStep,
+	baseline func(restarts map[string]int32) map[string]int32,
+) (replicationv1alpha1.WaveState, string, error) {
+	type waveWorkload struct {
+		Workload
+		name     string
+		checksum string
+		restarts map[string]int32
+	}
+	var workloads []waveWorkload
+	restarts := map[string]int32{}
+	for _, step := range steps {
+		listed, err := ListWorkloads(ctx, c, step.Namespace, kinds)
+		if err != nil {
+			return "", "", err
+		}
+		for _, workload := range listed {
+			checksum, ok := step.ChecksumOf(&workload)
+			if !ok {
+				continue
+			}
+			pods, err := podRestarts(ctx, c, workload.Object, annotationKey, checksum)
+			if err != nil {
+				return "", "", err
+			}
+			maps.Copy(restarts, pods)
+			workloads = append(workloads, waveWorkload{
+				Workload: workload,
+				name:     fmt.Sprintf("%s %s/%s", workload.Kind, step.Namespace, workload.Object.GetName()),
+				checksum: checksum,
+				restarts: pods,
+			})
+		}
+	}
+	var before map[string]int32
+	if baseline != nil {
+		before = baseline(restarts)
+	}
+
+	var progressing string
+	now := time.Now()
+	for _, workload := range workloads {
+		failed, err := isWorkloadFailed(ctx, c, workload.Object, annotationKey, workload.checksum, now)
+		if err != nil {
+			return "", "", err
+		}
+		if failed {
+			return replicationv1alpha1.WaveStateHalted, workload.name + " exceeded its progress deadline", nil
+		}
+		var restarted int32
+		for pod, count := range workload.restarts {
+			restarted += max(count-before[pod], 0)
+		}
+		if restarted > 0 {
+			return replicationv1alpha1.WaveStateHalted,
+				fmt.Sprintf("Containers of %s restarted %d times after the change", workload.name, restarted), nil
+		}
+		if progressing != "" {
+			continue
+		}
+		if workload.Template.Annotations[annotationKey] != workload.checksum {
+			progressing = fmt.Sprintf("Waiting for %s to restart", workload.name)
+		} else if !IsWorkloadReady(workload.Object) {
+			progressing = fmt.Sprintf("Waiting for %s to become available", workload.name)
+		}
+	}
+	if progressing != "" {
+		return replicationv1alpha1.WaveStateProgressing, progressing, nil
+	}
+	return replicationv1alpha1.WaveStateHealthy, "", nil
+}
+
+// workloadProgressDeadline is the time pods of StatefulSets and DaemonSets,
+// which have no progress deadline of their own, may stay not ready after they
+// were created for a change. It matches the default progress deadline of
+// Deployments.
+const workloadProgressDeadline = 10 * time.Minute
+
+// isWorkloadFailed returns true if a Deployment did not finish its rollout
+// within its progress deadline, or a pod of a StatefulSet or DaemonSet created
+// from the pod template carrying the checksum is not ready for longer than
+// workloadProgressDeadline.
+func isWorkloadFailed(
+	ctx context.Context,
+	c client.Client,
+	obj client.Object,
+	annotationKey, checksum string,
+	now time.Time,
+) (bool, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		for _, condition := range o.Status.Conditions {
+			if condition.Type == appsv1.DeploymentProgressing &&
+				condition.Status == corev1.ConditionFalse &&
+				condition.Reason == "ProgressDeadlineExceeded" {
+				return true, nil
+			}
+		}
+	case *appsv1.StatefulSet, *appsv1.DaemonSet:
+		pods, err := workloadPods(ctx, c, obj, annotationKey, checksum)
+		if err != nil {
+			return false, err
+		}
+		for i := range pods {
+			if since, ready := podReadySince(&pods[i]); !ready && now.Sub(since) > workloadProgressDeadline {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// podReadySince returns whether the pod is ready and since when it is in that
+// state, its creation if the Ready condition did not change since.
+func podReadySince(pod *corev1.Pod) (time.Time, bool) {
+	since := pod.CreationTimestamp.Time
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type != corev1.PodReady {
+			continue
+		}
+		if condition.LastTransitionTime.After(since) {
+			since = condition.LastTransitionTime.Time
+		}
+		return since, condition.Status == corev1.ConditionTrue
+	}
+	return since, false
+}
+
+// workloadSelector returns the pod selector of a Deployment, StatefulSet or
+// DaemonSet. Other kinds return nil.
+func workloadSelector(obj client.Object) *metav1.LabelSelector {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return o.Spec.Selector
+	case *appsv1.StatefulSet:
+		return o.Spec.Selector
+	case *appsv1.DaemonSet:
+		return o.Spec.Selector
+	}
+	return nil
+}
+
+// workloadPods returns the pods of a workload that were created from the pod
+// template carrying the checksum.
+func workloadPods(ctx context.Context, c client.Client, obj client.Object, annotationKey, checksum string) ([]corev1.Pod, error) {
+	selector := workloadSelector(obj)
+	if selector == nil {
+		return nil, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, nil
+	}
+
+	var pods corev1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(obj.GetNamespace()), client.MatchingLabelsSelector{Selector: s}); err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(pods.Items, func(pod corev1.Pod) bool {
+		return pod.Annotations[annotationKey] != checksum
+	}), nil
+}
+
+// podRestarts returns the container restarts of the pods of a workload that
+// were created from the pod template carrying the checksum, keyed by pod.
+func podRestarts(ctx context.Context, c client.Client, obj client.Object, annotationKey, checksum string) (map[string]int32, error) {
+	pods, err := workloadPods(ctx, c, obj, annotationKey, checksum)
+	if err != nil {
+		return nil, err
+	}
+	restarts := map[string]int32{}
+	for _, pod := range pods {
+		key := fmt.Sprintf("%s/%s/%s", pod.Namespace, pod.Name, pod.UID)
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			restarts[key] += status.RestartCount
+		}
+	}
+	return restarts, nil
+}
+
+// WaveTracker remembers since when the waves of a source are healthy, so the
+// delay of the next wave can be enforced, and the container restarts of their
+// pods when they started. The state is kept in memory, after a restart of the
+// operator delays and baselines start over.
+type WaveTracker struct {
+	mu        sync.Mutex
+	healthy   map[string]waveMark
+	baselines map[string]waveBaseline
+}
+
+// waveBaseline records the container restarts of the pods of a wave when it
+// started with a content hash.
+type waveBaseline struct {
+	hash     string
+	restarts map[string]int32
+}
+
+// waveMark records since when a wave is healthy with a content hash.
+type waveMark struct {
+	hash  string
+	since time.Time
+}
+
+// healthySince records that a wave of the source is healthy with the content
+// hash and returns since when. Without a tracker waves have no delay.
+func (t *WaveTracker) healthySince(source, wave, hash string, now time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.healthy == nil {
+		t.healthy = map[string]waveMark{}
+	}
+	key := source + "/" + wave
+	mark, ok := t.healthy[key]
+	if !ok || mark.hash != hash {
+		mark = waveMark{hash: hash, since: now}
+		t.healthy[key] = mark
+	}
+	return mark.since
+}
+
+// unhealthy forgets that a wave of the source was healthy.
+func (t *WaveTracker) unhealthy(source, wave string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.healthy, source+"/"+wave)
+}
+
+// restartBaseline returns the container restarts of the pods of a wave of the
+// source when the wave started with the content hash. The restarts are
+// recorded on the first call for the hash. Without a tracker all restarts
+// count.
+func (t *WaveTracker) restartBaseline(source, wave, hash string, restarts map[string]int32) map[string]int32 {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.baselines == nil {
+		t.baselines = map[string]waveBaseline{}
+	}
+	key := source + "/" + wave
+	baseline, ok := t.baselines[key]
+	if !ok || baseline.hash != hash {
+		baseline = waveBaseline{hash: hash, restarts: maps.Clone(restarts)}
+		t.baselines[key] = baseline
+	}
+	return baseline.restarts
+}
+
+// forget drops the waves of a deleted source.
+func (t *WaveTracker) forget(kind replicationv1alpha1.SourceKind, key types.NamespacedName) {
+	if t == nil {
+		return
+	}
+	prefix := fmt.Sprintf("%s/%s/%s/", kind, key.Namespace, key.Name)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	maps.DeleteFunc(t.healthy, func(k string, _ waveMark) bool { return strings.HasPrefix(k, prefix) })
+	maps.DeleteFunc(t.baselines, func(k string, _ waveBaseline) bool { return strings.HasPrefix(k, prefix) })
+}
@@ -0,0 +1,427 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+)
+
+func TestParseWaves(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		delay   string
+		want    []Wave
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "unnamed waves with delay",
+			value: "dev; staging-*, qa; prod",
+			delay: "10m",
+			want: []Wave{
+				{Name: "wave-1", Namespaces: []string{"dev"}},
+				{Name: "wave-2", Namespaces: []string{"staging-*", "qa"}, Delay: 10 * time.Minute},
+				{Name: "wave-3", Namespaces: []string{"prod"}, Delay: 10 * time.Minute},
+			},
+		},
+		{
+			name:  "named waves",
+			value: "canary=team-a; rest=team-*;",
+			want: []Wave{
+				{Name: "canary", Namespaces: []string{"team-a"}},
+				{Name: "rest", Namespaces: []string{"team-*"}},
+			},
+		},
+		{
+			name:    "wave without namespaces",
+			value:   "dev; prod=",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			value:   "a=dev; a=prod",
+			wantErr: true,
+		},
+		{
+			name:    "malformed pattern",
+			value:   "team-[a",
+			wantErr: true,
+		},
+		{
+			name:    "negative delay",
+			value:   "dev; prod",
+			delay:   "-1m",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWaves(tt.value, tt.delay)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWaves() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseWaves() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Name != tt.want[i].Name || !slices.Equal(got[i].Namespaces, tt.want[i].Namespaces) ||
+					got[i].Delay != tt.want[i].Delay {
+					t.Errorf("wave %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAssignWaves(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"env": "prod"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+	).Build()
+
+	waves := wavesFromAPI([]replicationv1alpha1.Wave{
+		{Name: "dev", Namespaces: []string{"dev-*"}},
+		{Name: "prod", NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+	})
+	groups, err := assignWaves(context.Background(), c, waves, []string{"dev-1", "team-a", "team-b", "dev-2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string][]string{"dev": {"dev-1", "dev-2"}, "prod": {"team-a"}, unassignedWaveName: {"team-b"}}
+	if len(groups) != len(want) {
+		t.Fatalf("expected %d waves, got %d", len(want), len(groups))
+	}
+	for _, group := range groups {
+		if !slices.Equal(group.Targets, want[group.Name]) {
+			t.Errorf("wave %s: expected %v, got %v", group.Name, want[group.Name], group.Targets)
+		}
+	}
+}
+
+func TestIsWorkloadFailed(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+	labels := map[string]string{"app": "db"}
+	pod := func(name, checksum string, created time.Duration, ready *corev1.PodCondition) *corev1.Pod {
+		p := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "apps",
+			Labels:            labels,
+			Annotations:       map[string]string{"replizieren.dev/checksum-secret-creds": checksum},
+			CreationTimestamp: metav1.NewTime(now.Add(-created)),
+		}}
+		if ready != nil {
+			p.Status.Conditions = []corev1.PodCondition{*ready}
+		}
+		return p
+	}
+	readySince := func(status corev1.ConditionStatus, since time.Duration) *corev1.PodCondition {
+		return &corev1.PodCondition{Type: corev1.PodReady, Status: status, LastTransitionTime: metav1.NewTime(now.Add(-since))}
+	}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps"},
+		Spec:       appsv1.StatefulSetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+	}
+
+	tests := []struct {
+		name string
+		pod  *corev1.Pod
+		want bool
+	}{
+		{name: "ready", pod: pod("db-0", "new", time.Hour, readySince(corev1.ConditionTrue, 50*time.Minute))},
+		{name: "starting", pod: pod("db-0", "new", 5*time.Minute, nil)},
+		{name: "never ready", pod: pod("db-0", "new", time.Hour, nil), want: true},
+		{name: "not ready past the deadline", pod: pod("db-0", "new", time.Hour, readySince(corev1.ConditionFalse, 20*time.Minute)), want: true},
+		{name: "recently not ready", pod: pod("db-0", "new", time.Hour, readySince(corev1.ConditionFalse, time.Minute))},
+		{name: "previous content", pod: pod("db-0", "old", time.Hour, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithObjects(statefulSet, tt.pod).Build()
+			failed, err := isWorkloadFailed(context.Background(), c, statefulSet, "replizieren.dev/checksum-secret-creds", "new", now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if failed != tt.want {
+				t.Errorf("expected failed %v, got %v", tt.want, failed)
+			}
+		})
+	}
+}
+
+func TestSyncSource_Waves(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Data:       map[string][]byte{"password": []byte("v1")},
+	}
+	replication := &replicationv1alpha1.Replication{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Spec: replicationv1alpha1.ReplicationSpec{
+			Source:  replicationv1alpha1.SourceReference{Kind: replicationv1alpha1.SourceKindSecret, Name: "creds"},
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"dev", "prod"}},
+			Options: replicationv1alpha1.ReplicationOptions{
+				RolloutOnUpdate: pointerTo(true),
+				Waves:           []replicationv1alpha1.Wave{{Name: "dev", Namespaces: []string{"dev"}}},
+			},
+		},
+	}
+	labels := map[string]string{"app": "app"}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "dev"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")},
+		},
+	}
+	f := newSyncFixture(t, source, replication, deploy, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod"}})
+	f.syncer.Waves = &WaveTracker{}
+	annotationKey := checksumAnnotationKey(source)
+
+	// rollOut marks the deployment as rolled out with a single pod of the
+	// current template that restarted the given number of times
+	rollOut := func(restarts int32) {
+		t.Helper()
+		f.get(deploy)
+		deploy.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: deploy.Generation,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		}
+		if err := f.client.Status().Update(f.ctx, deploy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "app-",
+				Namespace:    "dev",
+				Labels:       labels,
+				Annotations:  map[string]string{annotationKey: deploy.Spec.Template.Annotations[annotationKey]},
+			},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: restarts}}},
+		}
+		if err := f.client.Create(f.ctx, pod); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	prodPassword := func() string {
+		return f.secretData("prod", "creds", "password")
+	}
+	waveStates := func() []replicationv1alpha1.WaveState {
+		t.Helper()
+		f.get(replication)
+		var states []replicationv1alpha1.WaveState
+		for _, wave := range replication.Status.Waves {
+			states = append(states, wave.State)
+		}
+		return states
+	}
+
+	// The first wave is replicated and restarted, the second waits for it
+	results, requeueAfter := f.sync(source)
+	if results["prod"].Outcome != ReplicaPending || prodPassword() != "" {
+		t.Errorf("expected prod to wait for the dev wave, got %s", results["prod"].Outcome)
+	}
+	if requeueAfter != wavePollInterval {
+		t.Errorf("expected requeue after %v, got %v", wavePollInterval, requeueAfter)
+	}
+	if states := waveStates(); !slices.Equal(states, []replicationv1alpha1.WaveState{
+		replicationv1alpha1.WaveStateProgressing, replicationv1alpha1.WaveStatePending,
+	}) {
+		t.Errorf("unexpected wave states %v", states)
+	}
+	if !meta.IsStatusConditionTrue(replication.Status.Conditions, replicationv1alpha1.ConditionProgressing) {
+		t.Error("expected Progressing condition while waves are pending")
+	}
+
+	// Once dev is healthy, prod receives the change
+	rollOut(0)
+
+	_, requeueAfter = f.sync(source)
+	if prodPassword() != "v1" || requeueAfter != 0 {
+		t.Errorf("expected prod to be replicated, got %q, requeue after %v", prodPassword(), requeueAfter)
+	}
+	if states := waveStates(); !slices.Equal(states, []replicationv1alpha1.WaveState{
+		replicationv1alpha1.WaveStateHealthy, replicationv1alpha1.WaveStateHealthy,
+	}) {
+		t.Errorf("unexpected wave states %v", states)
+	}
+	if condition := meta.FindStatusCondition(replication.Status.Conditions, replicationv1alpha1.ConditionProgressing); condition == nil ||
+		condition.Reason != reasonWavesCompleted {
+		t.Errorf("expected completed waves, got %v", condition)
+	}
+
+	// Restarting containers in dev halt the propagation of the next change
+	source.Data["password"] = []byte("v2")
+	f.sync(source)
+	rollOut(3)
+	_, requeueAfter = f.sync(source)
+	if prodPassword() != "v1" || requeueAfter != 0 {
+		t.Errorf("expected prod to keep the previous content, got %q, requeue after %v", prodPassword(), requeueAfter)
+	}
+	if states := waveStates(); len(states) == 0 || states[0] != replicationv1alpha1.WaveStateHalted {
+		t.Errorf("expected the dev wave to be halted, got %v", states)
+	}
+	if condition := meta.FindStatusCondition(replication.Status.Conditions, replicationv1alpha1.ConditionProgressing); condition == nil ||
+		condition.Reason != reasonWaveHalted {
+		t.Errorf("expected WaveHalted condition, got %v", condition)
+	}
+}
+
+func TestSyncSource_WaveDelay(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "settings",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey: "dev,prod",
+				WavesKey:     "dev; prod",
+				WaveDelayKey: "10m",
+			},
+		},
+		Data: map[string]string{"level": "debug"},
+	}
+	f := newSyncFixture(t, source,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod"}},
+	)
+	f.syncer.Waves = &WaveTracker{}
+
+	results, requeueAfter := f.sync(source)
+	if results["dev"].Outcome != ReplicaCreated || results["prod"].Outcome != ReplicaPending {
+		t.Errorf("expected only dev to be replicated, got %s and %s", results["dev"].Outcome, results["prod"].Outcome)
+	}
+	if requeueAfter <= 9*time.Minute || requeueAfter > 10*time.Minute {
+		t.Errorf("expected requeue after the delay, got %v", requeueAfter)
+	}
+
+	// Without a tracker, waves are not delayed
+	f.syncer.Waves = nil
+	if results, _ = f.sync(source); results["prod"].Outcome != ReplicaCreated {
+		t.Errorf("expected prod to be replicated, got %s", results["prod"].Outcome)
+	}
+}
+
+func TestSyncSource_WaveRestartBaseline(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Data:       map[string][]byte{"password": []byte("v1")},
+	}
+	replication := &replicationv1alpha1.Replication{
+		ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "source-ns"},
+		Spec: replicationv1alpha1.ReplicationSpec{
+			Source:  replicationv1alpha1.SourceReference{Kind: replicationv1alpha1.SourceKindSecret, Name: "creds"},
+			Targets: replicationv1alpha1.TargetSelector{Namespaces: []string{"dev", "prod"}},
+			Options: replicationv1alpha1.ReplicationOptions{
+				RolloutOnUpdate: pointerTo(true),
+				Waves:           []replicationv1alpha1.Wave{{Name: "dev", Namespaces: []string{"dev"}}},
+			},
+		},
+	}
+	// The Deployment in dev already runs the content, its pod restarted
+	// before the change
+	labels := map[string]string{"app": "app"}
+	podSpec := podSpecUsingSecret("creds")
+	checksum, _ := consumedKeysHash(source, &podSpec)
+	annotationKey := checksumAnnotationKey(source)
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "dev"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotationKey: checksum}},
+				Spec:       podSpec,
+			},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app-1",
+			Namespace:   "dev",
+			Labels:      labels,
+			Annotations: map[string]string{annotationKey: checksum},
+		},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: 2}}},
+	}
+	f := newSyncFixture(t, source, replication, deploy, pod, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod"}})
+	f.syncer.Waves = &WaveTracker{}
+	firstWave := func() replicationv1alpha1.WaveStatus {
+		t.Helper()
+		f.get(replication)
+		if len(replication.Status.Waves) == 0 {
+			t.Fatal("expected wave status")
+		}
+		return replication.Status.Waves[0]
+	}
+
+	// Restarts from before the wave started do not fail it
+	f.sync(source)
+	if wave := firstWave(); wave.State != replicationv1alpha1.WaveStateHealthy {
+		t.Errorf("expected the dev wave to be healthy, got %s: %s", wave.State, wave.Message)
+	}
+	if password := f.secretData("prod", "creds", "password"); password != "v1" {
+		t.Errorf("expected prod to be replicated, got %q", password)
+	}
+
+	// Restarts since then do
+	f.get(pod)
+	pod.Status.ContainerStatuses[0].RestartCount = 3
+	if err := f.client.Status().Update(f.ctx, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.sync(source)
+	if wave := firstWave(); wave.State != replicationv1alpha1.WaveStateHalted {
+		t.Errorf("expected the dev wave to be halted, got %s", wave.State)
+	}
+}
+
+func TestWaveTracker_Forget(t *testing.T) {
+	tracker := &WaveTracker{}
+	now := time.Now()
+	tracker.healthySince("Secret/apps/creds", "dev", "v1", now)
+	tracker.restartBaseline("Secret/apps/creds", "dev", "v1", map[string]int32{"apps/app-1/": 2})
+	tracker.healthySince("Secret/apps/creds-2", "dev", "v1", now)
+
+	tracker.forget(replicationv1alpha1.SourceKindSecret, types.NamespacedName{Namespace: "apps", Name: "creds"})
+	if len(tracker.healthy) != 1 || len(tracker.baselines) != 0 {
+		t.Errorf("expected only the waves of the deleted source to be forgotten, got %v and %v", tracker.healthy, tracker.baselines)
+	}
+	if _, ok := tracker.healthy["Secret/apps/creds-2/dev"]; !ok {
+		t.Error("expected the waves of other sources to be kept")
+	}
+}
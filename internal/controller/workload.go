@@ -0,0 +1,282 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Workload is an object that runs pods from an embedded pod template.
+type Workload struct {
+	// Object is the workload itself.
+	Object client.Object
+	// Kind is the kind of the workload, e.g. "Deployment".
+	Kind string
+	// Template is the pod template used for new pods. For built-in kinds it
+	// points into Object, for custom kinds it is a decoded copy.
+	Template *corev1.PodTemplateSpec
+
+	// templatePath is the field path of the pod template of custom kinds.
+	templatePath []string
+}
+
+// setTemplateAnnotation sets an annotation on the pod template of the workload.
+func (w *Workload) setTemplateAnnotation(key, value string) error {
+	if w.templatePath == nil {
+		if w.Template.Annotations == nil {
+			w.Template.Annotations = map[string]string{}
+		}
+		w.Template.Annotations[key] = value
+		return nil
+	}
+	u, ok := w.Object.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("%s %s is not unstructured", w.Kind, w.Object.GetName())
+	}
+	fields := append(append([]string{}, w.templatePath...), "metadata", "annotations", key)
+	return unstructured.SetNestedField(u.Object, value, fields...)
+}
+
+// WorkloadKind is a custom resource kind that embeds a pod template, e.g. an
+// Argo Rollout. Its workloads are restarted like Deployments.
+type WorkloadKind struct {
+	// GroupVersionKind of the custom resource.
+	GroupVersionKind schema.GroupVersionKind
+	// TemplatePath is the field path of the pod template, e.g. spec.template.
+	TemplatePath []string
+}
+
+// ParseWorkloadKinds parses a comma-separated list of custom workload kinds in
+// the form group/version/Kind=<path>, where path is a JSONPath of fields
+// separated by dots, e.g. "argoproj.io/v1alpha1/Rollout=.spec.template".
+func ParseWorkloadKinds(value string) ([]WorkloadKind, error) {
+	var kinds []WorkloadKind
+	for _, entry := range SplitList(value) {
+		gvkString, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("workload kind %q: expected group/version/Kind=<path>", entry)
+		}
+		i := strings.LastIndex(gvkString, "/")
+		if i < 0 {
+			return nil, fmt.Errorf("workload kind %q: expected group/version/Kind", entry)
+		}
+		gv, err := schema.ParseGroupVersion(strings.TrimSpace(gvkString[:i]))
+		if err != nil {
+			return nil, fmt.Errorf("workload kind %q: %w", entry, err)
+		}
+		kind := strings.TrimSpace(gvkString[i+1:])
+		if kind == "" || gv.Version == "" {
+			return nil, fmt.Errorf("workload kind %q: expected group/version/Kind", entry)
+		}
+		fields, err := parseFieldPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("workload kind %q: %w", entry, err)
+		}
+		kinds = append(kinds, WorkloadKind{GroupVersionKind: gv.WithKind(kind), TemplatePath: fields})
+	}
+	return kinds, nil
+}
+
+// parseFieldPath parses a JSONPath that only selects fields, e.g.
+// "{.spec.template}" or ".spec.template".
+func parseFieldPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil, fmt.Errorf("empty template path")
+	}
+	fields := strings.Split(path, ".")
+	for _, field := range fields {
+		if field == "" || strings.ContainsAny(field, "[]*@$()?'\" ") {
+			return nil, fmt.Errorf("template path %q: only fields separated by dots are supported", path)
+		}
+	}
+	return fields, nil
+}
+
+// RestartWorkloadsFunc returns the checksum of the content of a resource a
+// workload consumes, or false if the workload is not restarted for the resource.
+type RestartWorkloadsFunc func(*Workload) (string, bool)
+
+// ListWorkloads returns the Deployments, StatefulSets, DaemonSets, CronJobs
+// and workloads of the custom kinds in the namespace. For CronJobs, the
+// template of their jobs is returned.
+func ListWorkloads(ctx context.Context, c client.Client, namespace string, kinds []WorkloadKind) ([]Workload, error) {
+	var workloads []Workload
+
+	var deploys appsv1.DeploymentList
+	if err := c.List(ctx, &deploys, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range deploys.Items {
+		d := &deploys.Items[i]
+		workloads = append(workloads, Workload{Object: d, Kind: "Deployment", Template: &d.Spec.Template})
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := c.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		workloads = append(workloads, Workload{Object: s, Kind: "StatefulSet", Template: &s.Spec.Template})
+	}
+
+	var daemonSets appsv1.DaemonSetList
+	if err := c.List(ctx, &daemonSets, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		d := &daemonSets.Items[i]
+		workloads = append(workloads, Workload{Object: d, Kind: "DaemonSet", Template: &d.Spec.Template})
+	}
+
+	var cronJobs batchv1.CronJobList
+	if err := c.List(ctx, &cronJobs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	for i := range cronJobs.Items {
+		j := &cronJobs.Items[i]
+		workloads = append(workloads, Workload{Object: j, Kind: "CronJob", Template: &j.Spec.JobTemplate.Spec.Template})
+	}
+
+	for _, kind := range kinds {
+		custom, err := listCustomWorkloads(ctx, c, namespace, kind)
+		if err != nil {
+			return nil, err
+		}
+		workloads = append(workloads, custom...)
+	}
+
+	return workloads, nil
+}
+
+// listCustomWorkloads returns the workloads of a custom kind in the namespace.
+// Kinds that are not installed in the cluster are skipped, as are objects
+// without a pod template at the configured path.
+func listCustomWorkloads(ctx context.Context, c client.Client, namespace string, kind WorkloadKind) ([]Workload, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(kind.GroupVersionKind.GroupVersion().WithKind(kind.GroupVersionKind.Kind + "List"))
+	if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var workloads []Workload
+	for i := range list.Items {
+		u := &list.Items[i]
+		raw, found, err := unstructured.NestedMap(u.Object, kind.TemplatePath...)
+		if err != nil || !found {
+			continue
+		}
+		template := &corev1.PodTemplateSpec{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, template); err != nil {
+			return nil, fmt.Errorf("failed to decode pod template of %s %s: %w", kind.GroupVersionKind.Kind, u.GetName(), err)
+		}
+		workloads = append(workloads, Workload{
+			Object:       u,
+			Kind:         kind.GroupVersionKind.Kind,
+			Template:     template,
+			templatePath: kind.TemplatePath,
+		})
+	}
+	return workloads, nil
+}
+
+// RestartWorkloads sets the checksum annotation on the pod template of all
+// workloads in the namespace that use the specified resource. Workloads that
+// already carry the checksum are left alone, so pods only restart when the
+// content they consume changed. With seed, workloads that never carried a
+// checksum record it in their own annotations instead, as their pods may
+// already run with the content, and are restarted once it changes. The
+// restarted workloads are returned. Custom kinds are patched as unstructured
+// objects. CronJobs are not restarted, but their next jobs pick up the change.
+func RestartWorkloads(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	kinds []WorkloadKind,
+	annotationKey string,
+	checksumOf RestartWorkloadsFunc,
+	seed bool,
+) ([]Workload, error) {
+	workloads, err := ListWorkloads(ctx, c, namespace, kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	var restarted []Workload
+
+	for i := range workloads {
+		workload := &workloads[i]
+		checksum, ok := checksumOf(workload)
+		if !ok {
+			continue
+		}
+		current, annotated := workload.Template.Annotations[annotationKey]
+		seeded, hasSeed := workload.Object.GetAnnotations()[annotationKey]
+		if !annotated {
+			current = seeded
+		}
+		if current == checksum {
+			continue
+		}
+		original, ok := workload.Object.DeepCopyObject().(client.Object)
+		if !ok {
+			continue
+		}
+		patch := client.MergeFrom(original)
+		annotations := workload.Object.GetAnnotations()
+		if seed && !annotated && !hasSeed {
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[annotationKey] = checksum
+			workload.Object.SetAnnotations(annotations)
+			if err := c.Patch(ctx, workload.Object, patch); err != nil {
+				return restarted, fmt.Errorf("failed to patch %s %s: %w", workload.Kind, workload.Object.GetName(), err)
+			}
+			continue
+		}
+		if hasSeed {
+			delete(annotations, annotationKey)
+			workload.Object.SetAnnotations(annotations)
+		}
+		if err := workload.setTemplateAnnotation(annotationKey, checksum); err != nil {
+			return restarted, err
+		}
+		if err := c.Patch(ctx, workload.Object, patch); err != nil {
+			return restarted, fmt.Errorf("failed to patch %s %s: %w", workload.Kind, workload.Object.GetName(), err)
+		}
+		restarted = append(restarted, *workload)
+	}
+	return restarted, nil
+}
@@ -0,0 +1,355 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func podSpecUsingSecret(name string) corev1.PodSpec {
+	return corev1.PodSpec{
+		Volumes: []corev1.Volume{{
+			Name:         "creds",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: name}},
+		}},
+	}
+}
+
+func TestRestartWorkloads(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	ctx := context.Background()
+
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps"},
+		Spec:       appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "apps"},
+		Spec:       appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "apps"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "@daily",
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+			},
+		},
+	}
+	unrelated := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("other")}},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).
+		WithObjects(statefulSet, daemonSet, cronJob, unrelated).Build()
+
+	checksumOf := func(w *Workload) (string, bool) { return "v1", IsPodSpecUsingSecret(&w.Template.Spec, "creds") }
+	if _, err := RestartWorkloads(ctx, c, "apps", nil, "replizieren.dev/checksum-secret-creds", checksumOf, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := func(name string) types.NamespacedName { return types.NamespacedName{Name: name, Namespace: "apps"} }
+	if err := c.Get(ctx, key("db"), statefulSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statefulSet.Spec.Template.Annotations["replizieren.dev/checksum-secret-creds"] != "v1" {
+		t.Error("expected StatefulSet to be restarted")
+	}
+	if err := c.Get(ctx, key("agent"), daemonSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if daemonSet.Spec.Template.Annotations["replizieren.dev/checksum-secret-creds"] != "v1" {
+		t.Error("expected DaemonSet to be restarted")
+	}
+	if err := c.Get(ctx, key("backup"), cronJob); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cronJob.Spec.JobTemplate.Spec.Template.Annotations["replizieren.dev/checksum-secret-creds"] != "v1" {
+		t.Error("expected the job template of the CronJob to be annotated")
+	}
+	if err := c.Get(ctx, key("web"), unrelated); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := unrelated.Spec.Template.Annotations["replizieren.dev/checksum-secret-creds"]; ok {
+		t.Error("expected Deployment that does not use the secret to be left alone")
+	}
+
+	// Workloads that carry the checksum are not patched again
+	resourceVersion := statefulSet.ResourceVersion
+	if _, err := RestartWorkloads(ctx, c, "apps", nil, "replizieren.dev/checksum-secret-creds", checksumOf, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get(ctx, key("db"), statefulSet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if statefulSet.ResourceVersion != resourceVersion {
+		t.Error("expected StatefulSet with the current checksum to not be patched")
+	}
+}
+
+func TestRestartWorkloads_Seed(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	ctx := context.Background()
+	const annotationKey = "replizieren.dev/checksum-secret-creds"
+
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "apps"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	stale := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "apps"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotationKey: "v0"}},
+			Spec:       podSpecUsingSecret("creds"),
+		}},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(deploy, stale).Build()
+	restart := func(checksum string, seed bool) []Workload {
+		t.Helper()
+		checksumOf := func(w *Workload) (string, bool) { return checksum, true }
+		restarted, err := RestartWorkloads(ctx, c, "apps", nil, annotationKey, checksumOf, seed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := c.Get(ctx, types.NamespacedName{Name: "web", Namespace: "apps"}, deploy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return restarted
+	}
+
+	// Workloads without a checksum are seeded, stale checksums still restart
+	restarted := restart("v1", true)
+	if len(restarted) != 1 || restarted[0].Object.GetName() != "api" {
+		t.Errorf("expected only the Deployment with a stale checksum to be restarted, got %d", len(restarted))
+	}
+	if deploy.Annotations[annotationKey] != "v1" || deploy.Spec.Template.Annotations[annotationKey] != "" {
+		t.Errorf("expected the checksum to be seeded without restart, got %v and %v",
+			deploy.Annotations, deploy.Spec.Template.Annotations)
+	}
+
+	// The seed counts as the current checksum
+	if restarted := restart("v1", false); len(restarted) != 0 {
+		t.Errorf("expected seeded Deployment to not be restarted, got %d", len(restarted))
+	}
+	if restarted := restart("v2", false); len(restarted) != 2 {
+		t.Errorf("expected both Deployments to be restarted for a change, got %d", len(restarted))
+	}
+	if _, ok := deploy.Annotations[annotationKey]; ok || deploy.Spec.Template.Annotations[annotationKey] != "v2" {
+		t.Errorf("expected the seed to be replaced by the pod template annotation, got %v and %v",
+			deploy.Annotations, deploy.Spec.Template.Annotations)
+	}
+}
+
+func TestChecksumAnnotationKey(t *testing.T) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db.credentials"}}
+	if got := checksumAnnotationKey(secret); got != "replizieren.dev/checksum-secret-db.credentials" {
+		t.Errorf("unexpected key %q", got)
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app"}}
+	if got := checksumAnnotationKey(cm); got != "replizieren.dev/checksum-configmap-app" {
+		t.Errorf("unexpected key %q", got)
+	}
+
+	long := strings.Repeat("a", 100)
+	first := checksumAnnotationKey(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: long + "-1"}})
+	second := checksumAnnotationKey(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: long + "-2"}})
+	if first == second {
+		t.Error("expected long names to result in distinct keys")
+	}
+	if errs := validation.IsQualifiedName(first); len(errs) > 0 {
+		t.Errorf("expected valid annotation key, got %v", errs)
+	}
+}
+
+func TestParseWorkloadKinds(t *testing.T) {
+	kinds, err := ParseWorkloadKinds("argoproj.io/v1alpha1/Rollout=.spec.template, example.com/v1/Worker={.spec.pod.template}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kinds) != 2 {
+		t.Fatalf("expected 2 kinds, got %d", len(kinds))
+	}
+	rollout := schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+	if kinds[0].GroupVersionKind != rollout {
+		t.Errorf("expected %v, got %v", rollout, kinds[0].GroupVersionKind)
+	}
+	if got := kinds[1].TemplatePath; len(got) != 3 || got[0] != "spec" || got[1] != "pod" || got[2] != "template" {
+		t.Errorf("expected path spec.pod.template, got %v", got)
+	}
+
+	for _, invalid := range []string{
+		"argoproj.io/v1alpha1/Rollout",
+		"Rollout=.spec.template",
+		"argoproj.io/v1alpha1/=.spec.template",
+		"argoproj.io/v1alpha1/Rollout=",
+		"argoproj.io/v1alpha1/Rollout=.spec.templates[0]",
+		"argoproj.io/v1alpha1/Rollout=.spec..template",
+	} {
+		if _, err := ParseWorkloadKinds(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestRestartWorkloads_CustomKind(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+	testScheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(testScheme)
+	testScheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	testScheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind("RolloutList"), &unstructured.UnstructuredList{})
+	ctx := context.Background()
+
+	newRollout := func(name, secret string) *unstructured.Unstructured {
+		template, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&corev1.PodTemplateSpec{Spec: podSpecUsingSecret(secret)})
+		rollout := &unstructured.Unstructured{Object: map[string]any{
+			"spec": map[string]any{"template": template},
+		}}
+		rollout.SetGroupVersionKind(gvk)
+		rollout.SetName(name)
+		rollout.SetNamespace("apps")
+		return rollout
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).
+		WithObjects(newRollout("api", "creds"), newRollout("web", "other")).Build()
+
+	kinds := []WorkloadKind{{GroupVersionKind: gvk, TemplatePath: []string{"spec", "template"}}}
+	checksumOf := func(w *Workload) (string, bool) { return "v1", IsPodSpecUsingSecret(&w.Template.Spec, "creds") }
+	if _, err := RestartWorkloads(ctx, c, "apps", kinds, "replizieren.dev/checksum-secret-creds", checksumOf, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	annotation := func(name string) string {
+		rollout := &unstructured.Unstructured{}
+		rollout.SetGroupVersionKind(gvk)
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: "apps"}, rollout); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		value, _, _ := unstructured.NestedString(rollout.Object, "spec", "template", "metadata", "annotations", "replizieren.dev/checksum-secret-creds")
+		return value
+	}
+	if annotation("api") == "" {
+		t.Error("expected Rollout using the secret to be restarted")
+	}
+	if annotation("web") != "" {
+		t.Error("expected Rollout that does not use the secret to be left alone")
+	}
+}
+
+func TestSyncSource_RestartsOnlyOnConsumedKeys(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "creds",
+			Namespace: "source-ns",
+			Annotations: map[string]string{
+				ReplicateKey:       "target-ns",
+				RolloutOnUpdateKey: "true",
+			},
+		},
+		Data: map[string][]byte{"username": []byte("app"), "password": []byte("v1")},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "target-ns"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Env: []corev1.EnvVar{{
+				Name: "USERNAME",
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "creds"},
+					Key:                  "username",
+				}},
+			}}}},
+		}}},
+	}
+	f := newSyncFixture(t, source, deploy)
+
+	f.sync(source)
+	checksum := f.checksum(source, "target-ns", "app")
+	if checksum == "" {
+		t.Fatal("expected deployment to carry the checksum of the consumed keys")
+	}
+
+	// Rotating a key the deployment does not consume leaves it alone
+	source.Data["password"] = []byte("v2")
+	f.sync(source)
+	if got := f.checksum(source, "target-ns", "app"); got != checksum {
+		t.Errorf("expected deployment to not be restarted, checksum changed to %s", got)
+	}
+
+	source.Data["username"] = []byte("other")
+	f.sync(source)
+	if got := f.checksum(source, "target-ns", "app"); got == checksum {
+		t.Error("expected deployment to be restarted when a consumed key changes")
+	}
+}
+
+func TestSyncSource_SeedsChecksumsOnFirstSync(t *testing.T) {
+	// A source without targets restarts the workloads in its namespace on every
+	// sync that is not a resync, as it cannot tell whether its content changed
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "creds",
+			Namespace:   "apps",
+			Annotations: map[string]string{RolloutOnUpdateKey: "true"},
+		},
+		Data: map[string][]byte{"password": []byte("v1")},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "apps"},
+		Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpecUsingSecret("creds")}},
+	}
+	f := newSyncFixture(t, source, deploy)
+	f.syncer.Seeds = &ChecksumSeeder{}
+
+	// The first sync after the operator started does not restart workloads
+	// that were never restarted for the source, e.g. after an upgrade
+	f.sync(source)
+	if f.checksum(source, "apps", "app") != "" {
+		t.Error("expected the first sync to not restart the Deployment")
+	}
+	f.get(deploy)
+	if deploy.Annotations[checksumAnnotationKey(source)] == "" {
+		t.Error("expected the checksum to be seeded on the Deployment")
+	}
+	f.expectEvents()
+
+	// Syncs without a change leave it alone, changes restart it
+	f.sync(source)
+	if f.checksum(source, "apps", "app") != "" {
+		t.Error("expected the Deployment to not be restarted without a change")
+	}
+	source.Data["password"] = []byte("v2")
+	f.sync(source)
+	if f.checksum(source, "apps", "app") == "" {
+		t.Error("expected the Deployment to be restarted for a change")
+	}
+	f.expectEvents("Normal RestartedBy")
+}
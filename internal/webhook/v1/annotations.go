@@ -0,0 +1,228 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	pathpkg "path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+	"github.com/Kammerdiener-Technologies/replizieren/internal/controller"
+)
+
+// namespacePatternChars matches the characters allowed in namespace patterns.
+var namespacePatternChars = regexp.MustCompile(`^[a-z0-9\-*?\[\]^]+$`)
+
+// Options configures the replizieren admission webhooks.
+type Options struct {
+	// ProtectedNamespaces can never be named as a replication target, in
+	// addition to the system namespaces.
+	ProtectedNamespaces []string
+
+	// OperatorUsername is the user name of the operator's service account,
+	// which is always allowed to modify replicas.
+	OperatorUsername string
+
+	// BreakGlassGroups are user groups that are allowed to modify replicas.
+	BreakGlassGroups []string
+}
+
+// isProtected returns true if the namespace may not be named as a target.
+func (o Options) isProtected(namespace string) bool {
+	return controller.IsSystemNamespace(namespace) || slices.Contains(o.ProtectedNamespaces, namespace)
+}
+
+// validateAnnotations validates the replizieren.dev annotations of a source.
+// All problems are reported at once as an Invalid error for the object.
+func validateAnnotations(obj client.Object, gk schema.GroupKind, opts Options) (admission.Warnings, error) {
+	annotations := obj.GetAnnotations()
+	path := field.NewPath("metadata", "annotations")
+
+	var warnings admission.Warnings
+	var errs field.ErrorList
+
+	replicateAll, replicateAllSet := annotations[controller.ReplicateAllKey]
+	if replicateAllSet && replicateAll != "true" && replicateAll != "false" {
+		errs = append(errs, field.NotSupported(path.Key(controller.ReplicateAllKey), replicateAll, []string{"true", "false"}))
+	}
+
+	for _, key := range []string{controller.RolloutOnUpdateKey, controller.RolloutWaitReadyKey, controller.PausedKey, controller.DryRunKey} {
+		if value, ok := annotations[key]; ok && value != "true" && value != "false" {
+			errs = append(errs, field.NotSupported(path.Key(key), value, []string{"true", "false"}))
+		}
+	}
+
+	if value, ok := annotations[controller.RolloutIntervalKey]; ok {
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			errs = append(errs, field.Invalid(path.Key(controller.RolloutIntervalKey), value, "must be a non-negative duration, e.g. 30s"))
+		}
+	}
+
+	if value, ok := annotations[controller.RolloutSelectorKey]; ok {
+		if _, err := controller.ParseRolloutSelector(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(controller.RolloutSelectorKey), value, err.Error()))
+		}
+	}
+
+	if value, ok := annotations[controller.OnUpdateJobKey]; ok {
+		if _, err := controller.ParseJobTemplateRef(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(controller.OnUpdateJobKey), value, err.Error()))
+		}
+	}
+
+	if value, ok := annotations[controller.RollbackKey]; ok {
+		if _, err := controller.ParseRollback(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(controller.RollbackKey), value, err.Error()))
+		}
+	}
+
+	if value, ok := annotations[controller.MaintenanceWindowKey]; ok {
+		if _, err := controller.ParseMaintenanceWindows(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(controller.MaintenanceWindowKey), value, err.Error()))
+		}
+	}
+	if scope, ok := annotations[controller.MaintenanceWindowScopeKey]; ok {
+		supported := []string{controller.WindowScopeAll, controller.WindowScopeRollout}
+		if !slices.Contains(supported, scope) {
+			errs = append(errs, field.NotSupported(path.Key(controller.MaintenanceWindowScopeKey), scope, supported))
+		}
+	}
+
+	if waves, ok := annotations[controller.WavesKey]; ok {
+		if _, err := controller.ParseWaves(waves, ""); err != nil {
+			errs = append(errs, field.Invalid(path.Key(controller.WavesKey), waves, err.Error()))
+		} else if strings.TrimSpace(waves) == "" {
+			errs = append(errs, field.Required(path.Key(controller.WavesKey), "must define at least one wave"))
+		}
+	}
+	if value, ok := annotations[controller.WaveDelayKey]; ok {
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			errs = append(errs, field.Invalid(path.Key(controller.WaveDelayKey), value, "must be a non-negative duration, e.g. 10m"))
+		}
+	}
+
+	if policy, ok := annotations[controller.ConflictPolicyKey]; ok {
+		supported := []string{string(replicationv1alpha1.ConflictPolicyOverwrite), string(replicationv1alpha1.ConflictPolicySkip)}
+		if !slices.Contains(supported, policy) {
+			errs = append(errs, field.NotSupported(path.Key(controller.ConflictPolicyKey), policy, supported))
+		}
+	}
+
+	if keys, ok := annotations[controller.KeysKey]; ok {
+		keysPath := path.Key(controller.KeysKey)
+		if strings.TrimSpace(keys) == "" {
+			errs = append(errs, field.Required(keysPath, "must list at least one key"))
+		}
+		for _, key := range controller.SplitList(keys) {
+			for _, msg := range validation.IsConfigMapKey(key) {
+				errs = append(errs, field.Invalid(keysPath, key, msg))
+			}
+		}
+	}
+
+	if replicate, ok := annotations[controller.ReplicateKey]; ok {
+		replicatePath := path.Key(controller.ReplicateKey)
+		switch {
+		case replicate == "false":
+		case replicate == "true" && replicateAll != "false":
+			warnings = append(warnings, fmt.Sprintf(
+				"%s: \"true\" is deprecated, use %s: \"true\" instead", controller.ReplicateKey, controller.ReplicateAllKey))
+		case strings.TrimSpace(replicate) == "":
+			errs = append(errs, field.Required(replicatePath, "must list at least one namespace"))
+		default:
+			if replicateAll == "true" {
+				errs = append(errs, field.Invalid(replicatePath, replicate,
+					fmt.Sprintf("conflicts with %s: \"true\", remove one of both annotations", controller.ReplicateAllKey)))
+			}
+			errs = append(errs, validateNamespaceList(replicatePath, replicate, obj.GetNamespace(), opts, &warnings)...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(gk, obj.GetName(), errs)
+}
+
+// validateNamespaceList validates a comma-separated list of target namespaces
+// and glob patterns.
+func validateNamespaceList(
+	path *field.Path,
+	value string,
+	sourceNamespace string,
+	opts Options,
+	warnings *admission.Warnings,
+) field.ErrorList {
+	namespaces := controller.SplitList(value)
+	errs := ValidateNamespaces(path, namespaces, opts)
+	if len(errs) == 0 && slices.Contains(namespaces, sourceNamespace) {
+		*warnings = append(*warnings, fmt.Sprintf("%s: the source namespace %q is ignored", controller.ReplicateKey, sourceNamespace))
+	}
+	return errs
+}
+
+// ValidateNamespaces validates target namespaces and glob patterns. Names must
+// be DNS-1123 labels that are not protected, patterns must be well-formed and
+// may not match a protected namespace.
+func ValidateNamespaces(path *field.Path, namespaces []string, opts Options) field.ErrorList {
+	var errs field.ErrorList
+	for _, ns := range namespaces {
+		if controller.IsNamespacePattern(ns) {
+			errs = append(errs, validateNamespacePattern(path, ns, opts)...)
+			continue
+		}
+		if msgs := validation.IsDNS1123Label(ns); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(path, ns, strings.Join(msgs, ", ")))
+			continue
+		}
+		if opts.isProtected(ns) {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("namespace %q is protected", ns)))
+		}
+	}
+	return errs
+}
+
+// validateNamespacePattern validates a glob pattern of target namespaces. The
+// pattern must be well-formed and may not match a protected namespace.
+func validateNamespacePattern(path *field.Path, pattern string, opts Options) field.ErrorList {
+	if !namespacePatternChars.MatchString(pattern) {
+		return field.ErrorList{field.Invalid(path, pattern,
+			"a namespace pattern may only contain lower case alphanumeric characters, '-' and the glob characters '*', '?', '[', ']' and '^'")}
+	}
+	if _, err := pathpkg.Match(pattern, ""); err != nil {
+		return field.ErrorList{field.Invalid(path, pattern, fmt.Sprintf("invalid glob pattern: %v", err))}
+	}
+
+	var errs field.ErrorList
+	for _, ns := range opts.ProtectedNamespaces {
+		if matched, _ := pathpkg.Match(pattern, ns); matched {
+			errs = append(errs, field.Forbidden(path, fmt.Sprintf("pattern %q matches protected namespace %q", pattern, ns)))
+		}
+	}
+	return errs
+}
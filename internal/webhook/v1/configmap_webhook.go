@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var configmaplog = logf.Log.WithName("configmap-resource")
+
+// SetupConfigMapWebhookWithManager registers the webhook for ConfigMap in the manager.
+func SetupConfigMapWebhookWithManager(mgr ctrl.Manager, opts Options) error {
+	if err := ctrl.NewWebhookManagedBy(mgr, &corev1.ConfigMap{}).
+		WithValidator(&ConfigMapCustomValidator{Options: opts}).
+		Complete(); err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr, &corev1.ConfigMap{}).
+		WithValidator(&ReplicaCustomValidator[*corev1.ConfigMap]{Client: mgr.GetClient(), Options: opts, Resource: corev1.Resource("configmaps")}).
+		WithValidatorCustomPath("/protect--v1-configmap").
+		Complete()
+}
+
+// The webhook intercepts every ConfigMap in the cluster, so it fails open to keep
+// the cluster usable while the operator is unavailable.
+// +kubebuilder:webhook:path=/validate--v1-configmap,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=configmaps,verbs=create;update,versions=v1,name=vconfigmap-v1.replizieren.dev,admissionReviewVersions=v1
+
+// Replicas are protected by a separate webhook that only receives objects
+// labelled as replica, see ReplicaCustomValidator.
+// +kubebuilder:webhook:path=/protect--v1-configmap,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=configmaps,verbs=update;delete,versions=v1,name=pconfigmap-v1.replizieren.dev,admissionReviewVersions=v1
+
+// ConfigMapCustomValidator struct is responsible for validating the replication
+// annotations of a ConfigMap when it is created or updated.
+type ConfigMapCustomValidator struct {
+	Options Options
+}
+
+var _ admission.Validator[*corev1.ConfigMap] = &ConfigMapCustomValidator{}
+
+// ValidateCreate implements admission.Validator so a webhook will be registered for the type ConfigMap.
+func (v *ConfigMapCustomValidator) ValidateCreate(_ context.Context, obj *corev1.ConfigMap) (admission.Warnings, error) {
+	configmaplog.V(1).Info("Validation for ConfigMap upon creation", "name", obj.GetName())
+
+	return validateAnnotations(obj, corev1.SchemeGroupVersion.WithKind("ConfigMap").GroupKind(), v.Options)
+}
+
+// ValidateUpdate implements admission.Validator so a webhook will be registered for the type ConfigMap.
+func (v *ConfigMapCustomValidator) ValidateUpdate(_ context.Context, _, newObj *corev1.ConfigMap) (admission.Warnings, error) {
+	configmaplog.V(1).Info("Validation for ConfigMap upon update", "name", newObj.GetName())
+
+	return validateAnnotations(newObj, corev1.SchemeGroupVersion.WithKind("ConfigMap").GroupKind(), v.Options)
+}
+
+// ValidateDelete implements admission.Validator so a webhook will be registered for the type ConfigMap.
+func (v *ConfigMapCustomValidator) ValidateDelete(_ context.Context, _ *corev1.ConfigMap) (admission.Warnings, error) {
+	return nil, nil
+}
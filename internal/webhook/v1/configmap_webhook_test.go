@@ -0,0 +1,73 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Kammerdiener-Technologies/replizieren/internal/controller"
+)
+
+var _ = Describe("ConfigMap Webhook", func() {
+	var (
+		obj       *corev1.ConfigMap
+		validator ConfigMapCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "app-config",
+				Namespace:   "default",
+				Annotations: map[string]string{},
+			},
+		}
+		validator = ConfigMapCustomValidator{}
+	})
+
+	Context("When creating or updating ConfigMap under Validating Webhook", func() {
+		It("Should admit valid options", func() {
+			obj.Annotations[controller.ReplicateAllKey] = "true"
+			obj.Annotations[controller.KeysKey] = "app.yaml,log-level"
+			obj.Annotations[controller.ConflictPolicyKey] = "Skip"
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny unknown conflict policies and invalid keys", func() {
+			obj.Annotations[controller.ReplicateAllKey] = "true"
+			obj.Annotations[controller.KeysKey] = "app/yaml"
+			obj.Annotations[controller.ConflictPolicyKey] = "Merge"
+			_, err := validator.ValidateUpdate(ctx, obj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("app/yaml"))
+			Expect(err.Error()).To(ContainSubstring("Merge"))
+		})
+
+		It("Should warn when the source namespace is listed", func() {
+			obj.Annotations[controller.ReplicateKey] = "default,team-a"
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/Kammerdiener-Technologies/replizieren/internal/controller"
+)
+
+// systemDeleters are the Kubernetes components that must be able to delete
+// replicas when a namespace is deleted or an owner goes away.
+var systemDeleters = []string{
+	"system:serviceaccount:kube-system:namespace-controller",
+	"system:serviceaccount:kube-system:generic-garbage-collector",
+}
+
+// DefaultBreakGlassGroups are allowed to modify replicas unless configured otherwise.
+var DefaultBreakGlassGroups = []string{"system:masters"}
+
+// ReplicaCustomValidator protects replicas from being changed or deleted by
+// anyone but the operator. Its webhook only receives objects labelled as
+// replica, so other Secrets and ConfigMaps are not slowed down or blocked by
+// an unavailable operator.
+type ReplicaCustomValidator[T client.Object] struct {
+	// Client looks up the source of a replica. Replicas whose source is gone
+	// or no longer targets their namespace are not protected.
+	Client  client.Client
+	Options Options
+	// Resource is reported in denials, e.g. secrets.
+	Resource schema.GroupResource
+}
+
+var _ admission.Validator[*corev1.Secret] = &ReplicaCustomValidator[*corev1.Secret]{}
+
+// ValidateCreate implements admission.Validator, creates are not intercepted.
+func (v *ReplicaCustomValidator[T]) ValidateCreate(_ context.Context, _ T) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate implements admission.Validator and denies updates of replicas.
+func (v *ReplicaCustomValidator[T]) ValidateUpdate(ctx context.Context, oldObj, _ T) (admission.Warnings, error) {
+	return nil, validateReplicaChange(ctx, v.Client, oldObj, v.Resource, v.Options, false)
+}
+
+// ValidateDelete implements admission.Validator and denies deletes of replicas.
+func (v *ReplicaCustomValidator[T]) ValidateDelete(ctx context.Context, obj T) (admission.Warnings, error) {
+	return nil, validateReplicaChange(ctx, v.Client, obj, v.Resource, v.Options, true)
+}
+
+// validateReplicaChange denies updates and deletes of replicas unless they are
+// requested by the operator itself or a break-glass group, or the replica is
+// orphaned.
+func validateReplicaChange(
+	ctx context.Context,
+	c client.Client,
+	existing client.Object,
+	gr schema.GroupResource,
+	opts Options,
+	isDelete bool,
+) error {
+	if !controller.IsReplica(existing) {
+		return nil
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	user := req.UserInfo
+	if opts.OperatorUsername != "" && user.Username == opts.OperatorUsername {
+		return nil
+	}
+	for _, group := range user.Groups {
+		if slices.Contains(opts.BreakGlassGroups, group) {
+			return nil
+		}
+	}
+	if isDelete && slices.Contains(systemDeleters, user.Username) {
+		return nil
+	}
+
+	if c != nil {
+		orphaned, err := controller.IsOrphaned(ctx, c, existing)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		if orphaned {
+			return nil
+		}
+	}
+
+	if ref, ok := controller.SourceOf(existing); ok {
+		return apierrors.NewForbidden(gr, existing.GetName(), fmt.Errorf(
+			"it is a replica of %s managed by replizieren, change the source instead", ref))
+	}
+	return apierrors.NewForbidden(gr, existing.GetName(), fmt.Errorf(
+		"it is a replica managed by replizieren, change the source instead"))
+}
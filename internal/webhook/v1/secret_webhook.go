@@ -0,0 +1,77 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var secretlog = logf.Log.WithName("secret-resource")
+
+// SetupSecretWebhookWithManager registers the webhook for Secret in the manager.
+func SetupSecretWebhookWithManager(mgr ctrl.Manager, opts Options) error {
+	if err := ctrl.NewWebhookManagedBy(mgr, &corev1.Secret{}).
+		WithValidator(&SecretCustomValidator{Options: opts}).
+		Complete(); err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr, &corev1.Secret{}).
+		WithValidator(&ReplicaCustomValidator[*corev1.Secret]{Client: mgr.GetClient(), Options: opts, Resource: corev1.Resource("secrets")}).
+		WithValidatorCustomPath("/protect--v1-secret").
+		Complete()
+}
+
+// The webhook intercepts every Secret in the cluster, so it fails open to keep
+// the cluster usable while the operator is unavailable.
+// +kubebuilder:webhook:path=/validate--v1-secret,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=secrets,verbs=create;update,versions=v1,name=vsecret-v1.replizieren.dev,admissionReviewVersions=v1
+
+// Replicas are protected by a separate webhook that only receives objects
+// labelled as replica, see ReplicaCustomValidator.
+// +kubebuilder:webhook:path=/protect--v1-secret,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=secrets,verbs=update;delete,versions=v1,name=psecret-v1.replizieren.dev,admissionReviewVersions=v1
+
+// SecretCustomValidator struct is responsible for validating the replication
+// annotations of a Secret when it is created or updated.
+type SecretCustomValidator struct {
+	Options Options
+}
+
+var _ admission.Validator[*corev1.Secret] = &SecretCustomValidator{}
+
+// ValidateCreate implements admission.Validator so a webhook will be registered for the type Secret.
+func (v *SecretCustomValidator) ValidateCreate(_ context.Context, obj *corev1.Secret) (admission.Warnings, error) {
+	secretlog.V(1).Info("Validation for Secret upon creation", "name", obj.GetName())
+
+	return validateAnnotations(obj, corev1.SchemeGroupVersion.WithKind("Secret").GroupKind(), v.Options)
+}
+
+// ValidateUpdate implements admission.Validator so a webhook will be registered for the type Secret.
+func (v *SecretCustomValidator) ValidateUpdate(_ context.Context, _, newObj *corev1.Secret) (admission.Warnings, error) {
+	secretlog.V(1).Info("Validation for Secret upon update", "name", newObj.GetName())
+
+	return validateAnnotations(newObj, corev1.SchemeGroupVersion.WithKind("Secret").GroupKind(), v.Options)
+}
+
+// ValidateDelete implements admission.Validator so a webhook will be registered for the type Secret.
+func (v *SecretCustomValidator) ValidateDelete(_ context.Context, _ *corev1.Secret) (admission.Warnings, error) {
+	return nil, nil
+}
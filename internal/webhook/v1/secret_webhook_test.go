@@ -0,0 +1,314 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+	"github.com/Kammerdiener-Technologies/replizieren/internal/controller"
+)
+
+var _ = Describe("Secret Webhook", func() {
+	var (
+		obj       *corev1.Secret
+		validator SecretCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "creds",
+				Namespace:   "default",
+				Annotations: map[string]string{},
+			},
+		}
+		validator = SecretCustomValidator{Options: Options{
+			ProtectedNamespaces: []string{"vault"},
+			OperatorUsername:    "system:serviceaccount:replizieren-system:replizieren-controller-manager",
+			BreakGlassGroups:    DefaultBreakGlassGroups,
+		}}
+	})
+
+	Context("When creating or updating Secret under Validating Webhook", func() {
+		It("Should admit secrets without replication annotations", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+		})
+
+		It("Should admit a valid namespace list", func() {
+			obj.Annotations[controller.ReplicateKey] = "team-a, team-b"
+			obj.Annotations[controller.RolloutOnUpdateKey] = "true"
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny invalid namespace names", func() {
+			obj.Annotations[controller.ReplicateKey] = "team-a,Team_B"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("Team_B"))
+		})
+
+		It("Should deny unknown values for replicate-all, rollout-on-update and paused", func() {
+			obj.Annotations[controller.ReplicateAllKey] = "yes"
+			obj.Annotations[controller.RolloutOnUpdateKey] = "always"
+			obj.Annotations[controller.PausedKey] = "1"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(controller.ReplicateAllKey))
+			Expect(err.Error()).To(ContainSubstring(controller.RolloutOnUpdateKey))
+			Expect(err.Error()).To(ContainSubstring(controller.PausedKey))
+		})
+
+		It("Should validate the rollout overrides", func() {
+			obj.Annotations[controller.RolloutIntervalKey] = "30s"
+			obj.Annotations[controller.RolloutWaitReadyKey] = "true"
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+
+			obj.Annotations[controller.RolloutIntervalKey] = "-1m"
+			obj.Annotations[controller.RolloutWaitReadyKey] = "maybe"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(controller.RolloutIntervalKey))
+			Expect(err.Error()).To(ContainSubstring(controller.RolloutWaitReadyKey))
+		})
+
+		It("Should validate the waves", func() {
+			obj.Annotations[controller.WavesKey] = "dev; staging=staging-*,qa; prod-*"
+			obj.Annotations[controller.WaveDelayKey] = "10m"
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+
+			obj.Annotations[controller.WavesKey] = "dev=dev; dev=qa"
+			obj.Annotations[controller.WaveDelayKey] = "later"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(controller.WavesKey))
+			Expect(err.Error()).To(ContainSubstring(controller.WaveDelayKey))
+		})
+
+		It("Should validate the rollout selector", func() {
+			obj.Annotations[controller.RolloutSelectorKey] = "tier=frontend; prod-*: tier in (frontend,api); dev: none"
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+
+			obj.Annotations[controller.RolloutSelectorKey] = "prod: tier in (frontend"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(controller.RolloutSelectorKey))
+		})
+
+		It("Should validate the on-update job", func() {
+			obj.Annotations[controller.OnUpdateJobKey] = "CronJob/db-migrate"
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+
+			obj.Annotations[controller.OnUpdateJobKey] = "deployment/db-migrate"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(controller.OnUpdateJobKey))
+		})
+
+		It("Should validate the maintenance windows", func() {
+			obj.Annotations[controller.MaintenanceWindowKey] = "0 22 * * mon-fri 4h; CRON_TZ=UTC 0 6 * * sat 2h"
+			obj.Annotations[controller.MaintenanceWindowScopeKey] = "Rollout"
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+
+			obj.Annotations[controller.MaintenanceWindowKey] = "0 25 * * * 4h"
+			obj.Annotations[controller.MaintenanceWindowScopeKey] = "Restarts"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(controller.MaintenanceWindowKey))
+			Expect(err.Error()).To(ContainSubstring(controller.MaintenanceWindowScopeKey))
+		})
+
+		It("Should validate the rollback revision", func() {
+			for _, value := range []string{"3", "previous"} {
+				obj.Annotations[controller.RollbackKey] = value
+				Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+			}
+
+			obj.Annotations[controller.RollbackKey] = "last"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(controller.RollbackKey))
+		})
+
+		It("Should deny a namespace list combined with replicate-all", func() {
+			obj.Annotations[controller.ReplicateAllKey] = "true"
+			obj.Annotations[controller.ReplicateKey] = "team-a"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("conflicts with"))
+		})
+
+		It("Should deny system and configured protected namespaces", func() {
+			obj.Annotations[controller.ReplicateKey] = "kube-system"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+			obj.Annotations[controller.ReplicateKey] = "vault"
+			_, err = validator.ValidateUpdate(ctx, obj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("is protected"))
+		})
+
+		It("Should admit glob patterns and deny malformed or protected ones", func() {
+			obj.Annotations[controller.ReplicateKey] = "team-*, env-[ab]"
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+
+			obj.Annotations[controller.ReplicateKey] = "team-[a"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("invalid glob pattern"))
+
+			obj.Annotations[controller.ReplicateKey] = "Team_*"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+
+			obj.Annotations[controller.ReplicateKey] = "va*"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("matches protected namespace"))
+		})
+
+		It("Should warn about the legacy replicate value", func() {
+			obj.Annotations[controller.ReplicateKey] = "true"
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(HaveLen(1))
+		})
+
+		It("Should accept a namespace named true when replicate-all is false", func() {
+			obj.Annotations[controller.ReplicateAllKey] = "false"
+			obj.Annotations[controller.ReplicateKey] = "true"
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should be rejected by the API server", func() {
+			obj.Namespace = "default"
+			obj.Annotations[controller.ReplicateKey] = "not a namespace"
+			err := k8sClient.Create(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		})
+	})
+
+	Context("When changing a replica under Validating Webhook", func() {
+		var (
+			replica   *corev1.Secret
+			protector *ReplicaCustomValidator[*corev1.Secret]
+		)
+
+		requestBy := func(username string, groups ...string) context.Context {
+			return admission.NewContextWithRequest(ctx, admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					UserInfo: authenticationv1.UserInfo{Username: username, Groups: groups},
+				},
+			})
+		}
+
+		BeforeEach(func() {
+			protector = &ReplicaCustomValidator[*corev1.Secret]{Options: validator.Options, Resource: corev1.Resource("secrets")}
+			replica = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "creds",
+					Namespace:   "team-a",
+					Labels:      map[string]string{controller.ReplicaLabel: "true"},
+					Annotations: map[string]string{controller.SourceAnnotation: "platform/creds"},
+				},
+			}
+		})
+
+		It("Should deny updates and deletes by other users and point to the source", func() {
+			userCtx := requestBy("alice", "system:authenticated")
+			_, err := protector.ValidateUpdate(userCtx, replica, replica)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("platform/creds"))
+
+			_, err = protector.ValidateDelete(userCtx, replica)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		})
+
+		It("Should admit changes by the operator and break-glass groups", func() {
+			_, err := protector.ValidateUpdate(requestBy(protector.Options.OperatorUsername), replica, replica)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = protector.ValidateDelete(requestBy("admin", "system:masters"), replica)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should admit deletes by the namespace controller", func() {
+			_, err := protector.ValidateDelete(requestBy("system:serviceaccount:kube-system:namespace-controller"), replica)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = protector.ValidateUpdate(requestBy("system:serviceaccount:kube-system:namespace-controller"), replica, replica)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		})
+
+		It("Should admit changes to replicas whose source no longer targets the namespace", func() {
+			testScheme := runtime.NewScheme()
+			Expect(clientgoscheme.AddToScheme(testScheme)).To(Succeed())
+			Expect(replicationv1alpha1.AddToScheme(testScheme)).To(Succeed())
+			source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:        "creds",
+				Namespace:   "platform",
+				Annotations: map[string]string{controller.ReplicateKey: "team-a"},
+			}}
+			protector.Client = fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+				source,
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+			).Build()
+			userCtx := requestBy("alice", "system:authenticated")
+
+			_, err := protector.ValidateUpdate(userCtx, replica, replica)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+
+			source.Annotations[controller.ReplicateKey] = "team-b"
+			Expect(protector.Client.Update(ctx, source)).To(Succeed())
+			_, err = protector.ValidateUpdate(userCtx, replica, replica)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(protector.Client.Delete(ctx, source)).To(Succeed())
+			_, err = protector.ValidateDelete(userCtx, replica)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should admit changes to objects that are not replicas", func() {
+			_, err := protector.ValidateDelete(requestBy("alice"), obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should leave replica protection to the replica webhook", func() {
+			_, err := validator.ValidateUpdate(requestBy("alice"), replica, replica)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = validator.ValidateDelete(requestBy("alice"), replica)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
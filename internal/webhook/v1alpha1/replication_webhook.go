@@ -0,0 +1,119 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+	"github.com/Kammerdiener-Technologies/replizieren/internal/controller"
+	webhookv1 "github.com/Kammerdiener-Technologies/replizieren/internal/webhook/v1"
+)
+
+// log is for logging in this package.
+var replicationlog = logf.Log.WithName("replication-resource")
+
+// SetupReplicationWebhookWithManager registers the webhook for Replication in the manager.
+func SetupReplicationWebhookWithManager(mgr ctrl.Manager, opts webhookv1.Options) error {
+	return ctrl.NewWebhookManagedBy(mgr, &replicationv1alpha1.Replication{}).
+		WithValidator(&ReplicationCustomValidator{Options: opts}).
+		Complete()
+}
+
+// Replications are created by tenants, so the webhook fails closed to keep them
+// from targeting protected namespaces while the operator is unavailable.
+// +kubebuilder:webhook:path=/validate-replication-replizieren-dev-v1alpha1-replication,mutating=false,failurePolicy=fail,sideEffects=None,groups=replication.replizieren.dev,resources=replications,verbs=create;update,versions=v1alpha1,name=vreplication-v1alpha1.replizieren.dev,admissionReviewVersions=v1
+
+// ReplicationCustomValidator struct is responsible for validating the targets
+// of a Replication when it is created or updated.
+type ReplicationCustomValidator struct {
+	Options webhookv1.Options
+}
+
+var _ admission.Validator[*replicationv1alpha1.Replication] = &ReplicationCustomValidator{}
+
+// ValidateCreate implements admission.Validator so a webhook will be registered for the type Replication.
+func (v *ReplicationCustomValidator) ValidateCreate(_ context.Context, obj *replicationv1alpha1.Replication) (admission.Warnings, error) {
+	replicationlog.V(1).Info("Validation for Replication upon creation", "name", obj.GetName())
+
+	return v.validate(obj)
+}
+
+// ValidateUpdate implements admission.Validator so a webhook will be registered for the type Replication.
+func (v *ReplicationCustomValidator) ValidateUpdate(_ context.Context, _, newObj *replicationv1alpha1.Replication) (admission.Warnings, error) {
+	replicationlog.V(1).Info("Validation for Replication upon update", "name", newObj.GetName())
+
+	return v.validate(newObj)
+}
+
+// ValidateDelete implements admission.Validator so a webhook will be registered for the type Replication.
+func (v *ReplicationCustomValidator) ValidateDelete(_ context.Context, _ *replicationv1alpha1.Replication) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the target namespaces of the Replication. Named targets must
+// be valid namespace names that are neither system nor protected namespaces.
+// All namespaces, namespace selectors and overwriting unmanaged objects are
+// reserved for ReplicationPolicies.
+// Entries with glob characters are patterns, which is reported as warning so
+// a typo does not silently select more namespaces than intended.
+func (v *ReplicationCustomValidator) validate(obj *replicationv1alpha1.Replication) (admission.Warnings, error) {
+	targets := obj.Spec.Targets
+	path := field.NewPath("spec", "targets")
+
+	var warnings admission.Warnings
+	errs := webhookv1.ValidateNamespaces(path.Child("namespaces"), targets.Namespaces, v.Options)
+	for _, ns := range targets.Namespaces {
+		switch {
+		case controller.IsNamespacePattern(ns):
+			warnings = append(warnings, fmt.Sprintf("spec.targets.namespaces: %q is a glob pattern and selects every matching namespace", ns))
+		case ns == obj.Namespace:
+			warnings = append(warnings, fmt.Sprintf("spec.targets.namespaces: the source namespace %q is ignored", ns))
+		}
+	}
+	for _, ns := range targets.ExcludeNamespaces {
+		if msgs := validation.IsDNS1123Label(ns); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(path.Child("excludeNamespaces"), ns, strings.Join(msgs, ", ")))
+		}
+	}
+	// Tenants may only name their targets, wider selections and overwriting
+	// objects of other teams are left to ReplicationPolicies
+	if targets.AllNamespaces {
+		errs = append(errs, field.Forbidden(path.Child("allNamespaces"), "use a ReplicationPolicy to replicate to all namespaces"))
+	}
+	if targets.NamespaceSelector != nil {
+		errs = append(errs, field.Forbidden(path.Child("namespaceSelector"), "use a ReplicationPolicy to select namespaces by label"))
+	}
+	if obj.Spec.Options.ConflictPolicy == replicationv1alpha1.ConflictPolicyOverwrite {
+		errs = append(errs, field.Forbidden(field.NewPath("spec", "options", "conflictPolicy"),
+			"Replications cannot overwrite objects not managed by replizieren"))
+	}
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(replicationv1alpha1.GroupVersion.WithKind("Replication").GroupKind(), obj.GetName(), errs)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	replicationv1alpha1 "github.com/Kammerdiener-Technologies/replizieren/api/v1alpha1"
+	webhookv1 "github.com/Kammerdiener-Technologies/replizieren/internal/webhook/v1"
+)
+
+var _ = Describe("Replication Webhook", func() {
+	var (
+		obj       *replicationv1alpha1.Replication
+		validator ReplicationCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &replicationv1alpha1.Replication{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "team-a"},
+			Spec: replicationv1alpha1.ReplicationSpec{
+				Source: replicationv1alpha1.SourceReference{Kind: replicationv1alpha1.SourceKindSecret, Name: "creds"},
+			},
+		}
+		validator = ReplicationCustomValidator{Options: webhookv1.Options{ProtectedNamespaces: []string{"vault"}}}
+	})
+
+	Context("When creating or updating Replication under Validating Webhook", func() {
+		It("Should admit valid target namespaces", func() {
+			obj.Spec.Targets.Namespaces = []string{"team-b", "team-c"}
+			obj.Spec.Targets.ExcludeNamespaces = []string{"team-d"}
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny system and protected namespaces", func() {
+			obj.Spec.Targets.Namespaces = []string{"kube-system", "vault"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`namespace "kube-system" is protected`))
+			Expect(err.Error()).To(ContainSubstring(`namespace "vault" is protected`))
+		})
+
+		It("Should deny invalid namespace names", func() {
+			obj.Spec.Targets.Namespaces = []string{"Team_B"}
+			obj.Spec.Targets.ExcludeNamespaces = []string{"team-*"}
+			_, err := validator.ValidateUpdate(ctx, obj, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.targets.namespaces"))
+			Expect(err.Error()).To(ContainSubstring("spec.targets.excludeNamespaces"))
+		})
+
+		It("Should deny patterns matching a protected namespace", func() {
+			obj.Spec.Targets.Namespaces = []string{"va*"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`matches protected namespace "vault"`))
+		})
+
+		It("Should deny selections and conflict policies reserved for policies", func() {
+			obj.Spec.Targets.AllNamespaces = true
+			obj.Spec.Targets.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "b"}}
+			obj.Spec.Options.ConflictPolicy = replicationv1alpha1.ConflictPolicyOverwrite
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.targets.allNamespaces"))
+			Expect(err.Error()).To(ContainSubstring("spec.targets.namespaceSelector"))
+			Expect(err.Error()).To(ContainSubstring("spec.options.conflictPolicy"))
+
+			obj.Spec.Targets.AllNamespaces = false
+			obj.Spec.Targets.NamespaceSelector = nil
+			obj.Spec.Options.ConflictPolicy = replicationv1alpha1.ConflictPolicySkip
+			_, err = validator.ValidateUpdate(ctx, obj, obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should warn about patterns and the source namespace", func() {
+			obj.Spec.Targets.Namespaces = []string{"team-*", "team-a"}
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(
+				ContainSubstring(`"team-*" is a glob pattern`),
+				ContainSubstring(`the source namespace "team-a" is ignored`),
+			))
+		})
+	})
+})